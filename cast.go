@@ -0,0 +1,242 @@
+package console
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ScreenRecorder is implemented by outputs that record the terminal session, so full-screen applications that bypass Print can be recorded too.
+type ScreenRecorder interface {
+	Output
+	// RecordScreen records data that has been written to the terminal directly, e.g. by a screen backend.
+	RecordScreen(data string)
+	// RecordKey records a key that has been read from the terminal directly.
+	RecordKey(key Key, r rune)
+}
+
+// OutputWrapper is implemented by outputs that decorate another output, so that optional interfaces of the wrapped output can be found.
+type OutputWrapper interface {
+	Output
+	// Unwrap returns the decorated output.
+	Unwrap() Output
+}
+
+// FindScreenRecorder returns the first ScreenRecorder in the chain of outputs wrapped by out.
+func FindScreenRecorder(out Output) (ScreenRecorder, bool) {
+	for out != nil {
+		if recorder, ok := out.(ScreenRecorder); ok {
+			return recorder, true
+		}
+		wrapper, ok := out.(OutputWrapper)
+		if !ok {
+			break
+		}
+		out = wrapper.Unwrap()
+	}
+	return nil, false
+}
+
+// CastOptions configures a CastRecorder.
+type CastOptions struct {
+	// Title is written to the header if not empty.
+	Title string
+	// RecordInput adds input events for keys and lines read from the console.
+	RecordInput bool
+}
+
+// castHeader is the first line of an asciinema v2 recording.
+type castHeader struct {
+	Version   int    `json:"version"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Timestamp int64  `json:"timestamp"`
+	Title     string `json:"title,omitempty"`
+}
+
+// CastRecorder records a console session in asciinema v2 format, which can be played with asciinema or embedded in web pages.
+//
+// Use WrapOutput and WrapInput to record the console output and input.
+type CastRecorder struct {
+	mutex       sync.Mutex
+	w           io.Writer
+	now         func() time.Time
+	start       time.Time
+	recordInput bool
+	err         error
+}
+
+// NewCastRecorder writes the header with the current terminal size to w and returns a recorder for the session. Size defaults to 80x24 if it is unknown.
+func NewCastRecorder(w io.Writer, opts CastOptions) (*CastRecorder, error) {
+	return newCastRecorder(w, opts, time.Now)
+}
+
+func newCastRecorder(w io.Writer, opts CastOptions, now func() time.Time) (*CastRecorder, error) {
+	width, height, err := GetSize()
+	if err != nil || width <= 0 || height <= 0 {
+		width, height = 80, 24
+	}
+
+	r := &CastRecorder{w: w, now: now, start: now(), recordInput: opts.RecordInput}
+	data, err := json.Marshal(castHeader{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: r.start.Unix(),
+		Title:     opts.Title,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// WrapOutput returns an output that records everything printed to out.
+func (r *CastRecorder) WrapOutput(out Output) Output {
+	return &castOutput{Output: out, recorder: r}
+}
+
+// WrapInput returns an input that records keys and lines read from in, if RecordInput is set. Passwords are never recorded.
+func (r *CastRecorder) WrapInput(in Input) Input {
+	return &castInput{Input: in, recorder: r}
+}
+
+// Err returns the first error that occurred while writing the recording.
+func (r *CastRecorder) Err() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.err
+}
+
+// write appends an event of the given type. Errors are kept and returned by Err.
+func (r *CastRecorder) write(eventType, data string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.err != nil {
+		return
+	}
+
+	elapsed := r.now().Sub(r.start).Seconds()
+	line, err := json.Marshal([]any{elapsed, eventType, data})
+	if err == nil {
+		_, err = r.w.Write(append(line, '\n'))
+	}
+	r.err = err
+}
+
+// output records data as output event. Line breaks are converted to CRLF like a terminal in cooked mode does.
+func (r *CastRecorder) output(data string) {
+	if len(data) > 0 {
+		r.write("o", strings.ReplaceAll(strings.ReplaceAll(data, "\r\n", "\n"), "\n", "\r\n"))
+	}
+}
+
+// input records data as input event if RecordInput is set.
+func (r *CastRecorder) input(data string) {
+	if r.recordInput && len(data) > 0 {
+		r.write("i", data)
+	}
+}
+
+type castOutput struct {
+	Output
+	recorder *CastRecorder
+}
+
+func (o *castOutput) Print(str string) (int, error) {
+	o.recorder.output(str)
+	return o.Output.Print(str)
+}
+
+func (o *castOutput) RecordScreen(data string) {
+	if len(data) > 0 {
+		o.recorder.write("o", data)
+	}
+}
+
+func (o *castOutput) RecordKey(key Key, r rune) {
+	o.recorder.input(keySequence(key, r))
+}
+
+func (o *castOutput) Unwrap() Output {
+	return o.Output
+}
+
+type castInput struct {
+	Input
+	recorder *CastRecorder
+}
+
+func (i *castInput) ReadLine() (string, error) {
+	line, err := i.Input.ReadLine()
+	if err == nil {
+		i.recorder.input(line + "\r")
+	}
+	return line, err
+}
+
+func (i *castInput) ReadKey() (Key, rune, error) {
+	key, r, err := i.Input.ReadKey()
+	if err == nil {
+		i.recorder.input(keySequence(key, r))
+	}
+	return key, r, err
+}
+
+func (i *castInput) IsInteractive() bool {
+	if in, ok := i.Input.(InteractiveInput); ok {
+		return in.IsInteractive()
+	}
+	return true
+}
+
+// keySequence returns the bytes a terminal sends for the given key.
+func keySequence(key Key, r rune) string {
+	switch key {
+	case KeyEscape:
+		return "\x1b"
+	case KeyCtrlC:
+		return "\x03"
+	case KeyCtrlW:
+		return "\x17"
+	case KeyCtrlS:
+		return "\x13"
+	case KeyUp:
+		return "\x1b[A"
+	case KeyDown:
+		return "\x1b[B"
+	case KeyRight:
+		return "\x1b[C"
+	case KeyLeft:
+		return "\x1b[D"
+	case KeyHome:
+		return "\x1b[H"
+	case KeyEnd:
+		return "\x1b[F"
+	case KeyPageUp:
+		return "\x1b[5~"
+	case KeyPageDown:
+		return "\x1b[6~"
+	case KeyBackspace:
+		return "\x7f"
+	case KeyDelete:
+		return "\x1b[3~"
+	case KeyEnter:
+		return "\r"
+	case KeyTab:
+		return "\t"
+	case KeySpace:
+		return " "
+	case KeyAlt:
+		return "\x1b" + string(r)
+	}
+	if r != 0 {
+		return string(r)
+	}
+	return ""
+}
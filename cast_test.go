@@ -0,0 +1,157 @@
+package console
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testOutput struct {
+	strings.Builder
+}
+
+func (o *testOutput) Print(str string) (int, error) { return o.WriteString(str) }
+func (o *testOutput) GetSize() (int, int, error)    { return 100, 30, nil }
+func (o *testOutput) SupportsColors() bool          { return false }
+func (o *testOutput) Exit(int)                      {}
+
+type testInput struct {
+	lines []string
+	keys  []Key
+}
+
+func (i *testInput) ReadLine() (string, error) {
+	line := i.lines[0]
+	i.lines = i.lines[1:]
+	return line, nil
+}
+func (i *testInput) ReadPassword() (string, error) { return i.ReadLine() }
+func (i *testInput) BeginReadKey() error           { return nil }
+func (i *testInput) ReadKey() (Key, rune, error) {
+	key := i.keys[0]
+	i.keys = i.keys[1:]
+	if key == 0 {
+		return 0, 'x', nil
+	}
+	return key, 0, nil
+}
+func (i *testInput) EndReadKey() error { return nil }
+
+// newTestCastRecorder returns a recorder whose clock advances by half a second on every event.
+func newTestCastRecorder(t *testing.T, w *bytes.Buffer, opts CastOptions) *CastRecorder {
+	clock := time.Unix(1000, 0)
+	recorder, err := newCastRecorder(w, opts, func() time.Time {
+		defer func() { clock = clock.Add(500 * time.Millisecond) }()
+		return clock
+	})
+	require.NoError(t, err)
+	return recorder
+}
+
+func TestCastRecorder(t *testing.T) {
+	oldOutput := DefaultOutput
+	defer func() { DefaultOutput = oldOutput }()
+	out := &testOutput{}
+	DefaultOutput = out
+
+	var buffer bytes.Buffer
+	recorder := newTestCastRecorder(t, &buffer, CastOptions{Title: "demo"})
+	output := recorder.WrapOutput(out)
+	input := recorder.WrapInput(&testInput{lines: []string{"a"}, keys: []Key{KeyUp}})
+
+	_, err := output.Print("foo\nbar\r\n")
+	require.NoError(t, err)
+	line, err := input.ReadLine()
+	require.NoError(t, err)
+	assert.Equal(t, "a", line)
+	_, _, err = input.ReadKey()
+	require.NoError(t, err)
+	recorder.WrapOutput(out).(ScreenRecorder).RecordScreen("\x1b[H")
+
+	require.NoError(t, recorder.Err())
+	assert.Equal(t, "foo\nbar\r\n", out.String())
+	assert.Equal(t, strings.Join([]string{
+		`{"version":2,"width":100,"height":30,"timestamp":1000,"title":"demo"}`,
+		`[0.5,"o","foo\r\nbar\r\n"]`,
+		`[1,"o","\u001b[H"]`,
+		"",
+	}, "\n"), buffer.String())
+}
+
+func TestCastRecorderInput(t *testing.T) {
+	var buffer bytes.Buffer
+	recorder := newTestCastRecorder(t, &buffer, CastOptions{RecordInput: true})
+	input := recorder.WrapInput(&testInput{lines: []string{"a", "secret"}, keys: []Key{KeyUp, 0, KeyEnter}})
+
+	_, err := input.ReadLine()
+	require.NoError(t, err)
+	_, err = input.ReadPassword()
+	require.NoError(t, err)
+	for range 3 {
+		_, _, err = input.ReadKey()
+		require.NoError(t, err)
+	}
+	recorder.WrapOutput(&testOutput{}).(ScreenRecorder).RecordKey(KeyAlt, 'b')
+
+	lines := strings.Split(buffer.String(), "\n")
+	assert.Equal(t, []string{
+		`[0.5,"i","a\r"]`,
+		`[1,"i","\u001b[A"]`,
+		`[1.5,"i","x"]`,
+		`[2,"i","\r"]`,
+		`[2.5,"i","\u001bb"]`,
+		"",
+	}, lines[1:])
+}
+
+type failingWriter struct {
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	if w.writes > 1 {
+		return 0, errors.New("disk full")
+	}
+	return len(p), nil
+}
+
+func TestCastRecorderError(t *testing.T) {
+	w := &failingWriter{}
+	recorder, err := NewCastRecorder(w, CastOptions{})
+	require.NoError(t, err)
+
+	output := recorder.WrapOutput(&testOutput{})
+	_, err = output.Print("a")
+	// output is not affected by recording errors
+	require.NoError(t, err)
+	_, _ = output.Print("b")
+	assert.EqualError(t, recorder.Err(), "disk full")
+	assert.Equal(t, 2, w.writes)
+}
+
+func TestFindScreenRecorder(t *testing.T) {
+	var buffer bytes.Buffer
+	recorder := newTestCastRecorder(t, &buffer, CastOptions{})
+	output := recorder.WrapOutput(&testOutput{})
+
+	found, ok := FindScreenRecorder(&wrappingOutput{Output: output})
+	assert.True(t, ok)
+	assert.Same(t, output, found)
+
+	_, ok = FindScreenRecorder(&wrappingOutput{Output: &testOutput{}})
+	assert.False(t, ok)
+}
+
+type wrappingOutput struct {
+	Output
+}
+
+func (o *wrappingOutput) Unwrap() Output {
+	return o.Output
+}
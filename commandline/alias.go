@@ -0,0 +1,202 @@
+package commandline
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+
+	"github.com/DENICeG/go-console/v2"
+)
+
+// SetAlias defines name as an alternative for the given command tokens. Arguments passed to the alias are appended to target.
+//
+// Aliases are expanded by ExecCommand before the command is dispatched and can refer to other aliases.
+func (b *Environment) SetAlias(name string, target ...string) {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+	b.aliases[name] = target
+}
+
+// RemoveAlias removes an alias and returns true if it was existent before.
+func (b *Environment) RemoveAlias(name string) bool {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+	_, exists := b.aliases[name]
+	if exists {
+		delete(b.aliases, name)
+	}
+	return exists
+}
+
+// Alias returns the command tokens of an alias.
+func (b *Environment) Alias(name string) ([]string, bool) {
+	b.stateMutex.RLock()
+	defer b.stateMutex.RUnlock()
+	target, exists := b.aliases[name]
+	return target, exists
+}
+
+// expandAliases replaces the first token of cmd as long as it denotes an alias.
+//
+// A name that has already been expanded ends the expansion, so aliases can refer to a command of the same name. All other loops are returned as error.
+func (b *Environment) expandAliases(cmd []string) ([]string, error) {
+	expanded := make(map[string]bool)
+	chain := make([]string, 0)
+	for len(cmd) > 0 {
+		target, exists := b.Alias(cmd[0])
+		if !exists {
+			break
+		}
+
+		chain = append(chain, cmd[0])
+		if expanded[cmd[0]] {
+			if _, exists := b.command(cmd[0]); exists {
+				break
+			}
+			return nil, fmt.Errorf("alias loop detected: %s", strings.Join(chain, " -> "))
+		}
+		expanded[cmd[0]] = true
+
+		cmd = append(append(make([]string, 0, len(target)+len(cmd)-1), target...), cmd[1:]...)
+	}
+	return cmd, nil
+}
+
+// expandAliasesAt expands the aliases of cmd and returns the shifted entry index.
+func (b *Environment) expandAliasesAt(cmd []string, entryIndex int) ([]string, int, bool) {
+	expanded, err := b.expandAliases(cmd)
+	if err != nil || len(expanded) == 0 {
+		return nil, 0, false
+	}
+	return expanded, entryIndex + len(expanded) - len(cmd), true
+}
+
+// aliasOptions returns sorted completion options for all aliases.
+func (b *Environment) aliasOptions() []CompletionOption {
+	aliases := b.aliasMap()
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	options := make([]CompletionOption, len(names))
+	for i, name := range names {
+		options[i] = NewDescribedCompletionOption(name, "alias for "+GetCommandString(aliases[name]), false)
+	}
+	return options
+}
+
+// aliasMap returns a copy of all aliases.
+func (b *Environment) aliasMap() map[string][]string {
+	b.stateMutex.RLock()
+	defer b.stateMutex.RUnlock()
+	return maps.Clone(b.aliases)
+}
+
+type aliasCommand struct {
+	name string
+	env  *Environment
+}
+
+// NewAliasCommand returns a named command to define aliases at runtime like alias ll="ls -l".
+//
+// Without arguments, all aliases are listed. Passing only a name prints the definition of that alias.
+func NewAliasCommand(name string, env *Environment) Command {
+	return Describe(&aliasCommand{name: name, env: env}, CommandInfo{
+		Description: "define or list aliases",
+		Usage:       fmt.Sprintf("Usage: %s [name[=command]...]\n", name),
+	})
+}
+
+func (c *aliasCommand) Name() string {
+	return c.name
+}
+
+func (c *aliasCommand) GetCompletionOptions(currentCommand []string, entryIndex int) []CompletionOption {
+	if strings.Contains(currentCommand[entryIndex], "=") {
+		return nil
+	}
+	return c.env.aliasOptions()
+}
+
+func (c *aliasCommand) printAlias(name string, target []string) error {
+	_, err := console.Printlnf("%s %s=%s", c.name, name, Quote(GetCommandString(target)))
+	return err
+}
+
+func (c *aliasCommand) Exec(args []string) error {
+	if len(args) == 0 {
+		aliases := c.env.aliasMap()
+		names := make([]string, 0, len(aliases))
+		for name := range aliases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := c.printAlias(name, aliases[name]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, arg := range args {
+		name, value, isDefinition := strings.Cut(arg, "=")
+		if len(name) == 0 {
+			return fmt.Errorf("invalid alias definition %q", arg)
+		}
+
+		if !isDefinition {
+			target, exists := c.env.Alias(name)
+			if !exists {
+				return fmt.Errorf("unknown alias %q", name)
+			}
+			if err := c.printAlias(name, target); err != nil {
+				return err
+			}
+			continue
+		}
+
+		target, isComplete := ParseCommand(value)
+		if !isComplete {
+			return fmt.Errorf("incomplete alias definition %q", arg)
+		}
+		c.env.SetAlias(name, target...)
+	}
+	return nil
+}
+
+type unaliasCommand struct {
+	name string
+	env  *Environment
+}
+
+// NewUnaliasCommand returns a named command to remove aliases at runtime.
+func NewUnaliasCommand(name string, env *Environment) Command {
+	return Describe(&unaliasCommand{name: name, env: env}, CommandInfo{
+		Description: "remove aliases",
+		Usage:       fmt.Sprintf("Usage: %s <name>...\n", name),
+	})
+}
+
+func (c *unaliasCommand) Name() string {
+	return c.name
+}
+
+func (c *unaliasCommand) GetCompletionOptions(_ []string, _ int) []CompletionOption {
+	return c.env.aliasOptions()
+}
+
+func (c *unaliasCommand) Exec(args []string) error {
+	if len(args) == 0 {
+		return ErrInvalidUsage{Err: fmt.Errorf("missing alias name"), Usage: fmt.Sprintf("Usage: %s <name>...\n", c.name)}
+	}
+	for _, name := range args {
+		if !c.env.RemoveAlias(name) {
+			return fmt.Errorf("unknown alias %q", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package commandline
+
+import (
+	"testing"
+
+	"github.com/DENICeG/go-console/v2/consoletest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandAliases(t *testing.T) {
+	cle, _, _ := prepareTestCLE()
+	cle.SetAlias("p", "print", "-v")
+	cle.SetAlias("pp", "p", "twice")
+	cle.SetAlias("print", "print", "--color")
+	cle.SetAlias("a", "b")
+	cle.SetAlias("b", "a")
+
+	cmd, err := cle.expandAliases([]string{"pp", "foo"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"print", "--color", "-v", "twice", "foo"}, cmd)
+
+	cmd, err = cle.expandAliases([]string{"exit"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"exit"}, cmd)
+
+	_, err = cle.expandAliases([]string{"a"})
+	assert.EqualError(t, err, "alias loop detected: a -> b -> a")
+}
+
+func TestCommandLineEnvironmentAlias(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		cle, lastCompletionIndex, sb := prepareTestCLE()
+		cle.RegisterCommand(NewAliasCommand("alias", cle))
+		cle.RegisterCommand(NewUnaliasCommand("unalias", cle))
+		cle.SetAlias("q", "exit")
+
+		input.PutString("alias pf=\"print foo\"\npf ba\t\nalias\nunalias pf\npf\nq\n")
+		require.NoError(t, cle.Run())
+		input.AssertBufferConsumed(t)
+
+		// "pf ba" is completed as "print foo ba"
+		assert.Equal(t, 2, *lastCompletionIndex)
+		assert.Equal(t, ">foo<>bar<|", sb.String())
+		assert.Contains(t, output.String(), "alias pf=\"print foo\"\nalias q=exit\n")
+		assert.Contains(t, output.String(), "Unknown command \"pf\"")
+	})
+}
+
+func TestAliasCompletion(t *testing.T) {
+	cle, _, _ := prepareTestCLE()
+	cle.SetAlias("pf", "print", "foo")
+	cle.SetAlias("print", "print")
+
+	options := matchOptions(cle.GetCompletionOptions([]string{"p"}, 0), "p", nil)
+	assert.Equal(t, []string{"pf", "print"}, replacements(options))
+	assert.Equal(t, "alias for print foo", getDescription(options[0]))
+
+	// completion of arguments is delegated to the target command
+	assert.Equal(t, replacements(cle.GetCompletionOptions([]string{"print", "foo", ""}, 2)), replacements(cle.GetCompletionOptions([]string{"pf", ""}, 1)))
+	assert.NotEmpty(t, cle.GetCompletionOptions([]string{"pf", ""}, 1))
+}
+
+func TestAliasValidation(t *testing.T) {
+	cle := NewEnvironment()
+	cle.RegisterCommand(NewValidatedCustomCommand("check", nil,
+		func(args []string) error {
+			for i, a := range args {
+				if a == "bad" {
+					return NewErrInvalidArgument(i, "bad argument")
+				}
+			}
+			return nil
+		}, nil))
+	cle.SetAlias("c", "check", "ok")
+	cle.SetAlias("cb", "check", "bad")
+
+	assert.Equal(t, NewErrInvalidArgument(2, "bad argument"), cle.ValidateCommand([]string{"c", "ok", "bad"}))
+	// errors within the alias mark the alias name
+	assert.Equal(t, NewErrInvalidArgument(0, "bad argument"), cle.ValidateCommand([]string{"cb", "ok"}))
+}
@@ -21,9 +21,17 @@ type CompletionOption interface {
 	IsPartial() bool
 }
 
+// DescribedCompletionOption is implemented by completion options with a description that is displayed next to the option.
+type DescribedCompletionOption interface {
+	CompletionOption
+	// Description returns a short explanation of the option.
+	Description() string
+}
+
 type completionOption struct {
 	label       string
 	replacement string
+	description string
 	isPartial   bool
 }
 
@@ -37,6 +45,11 @@ func NewLabelledCompletionOption(label, replacement string, isPartial bool) Comp
 	return &completionOption{label: label, replacement: replacement, isPartial: isPartial}
 }
 
+// NewDescribedCompletionOption returns a new completion option with description.
+func NewDescribedCompletionOption(replacement, description string, isPartial bool) CompletionOption {
+	return &completionOption{replacement: replacement, description: description, isPartial: isPartial}
+}
+
 func (c *completionOption) String() string {
 	if len(c.label) > 0 {
 		return c.label
@@ -52,6 +65,28 @@ func (c *completionOption) IsPartial() bool {
 	return c.isPartial
 }
 
+func (c *completionOption) Description() string {
+	return c.description
+}
+
+// getDescription returns the description of a completion option or an empty string if it does not have one.
+func getDescription(option CompletionOption) string {
+	if described, ok := option.(DescribedCompletionOption); ok {
+		return described.Description()
+	}
+	return ""
+}
+
+// hasDescriptions returns true if at least one option has a description.
+func hasDescriptions(options []CompletionOption) bool {
+	for _, o := range options {
+		if len(getDescription(o)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // PrepareCompletionOptions returns a list of completion options with given isPartial flag.
 func PrepareCompletionOptions(list []string, isPartial bool) []CompletionOption {
 	options := make([]CompletionOption, len(list))
@@ -0,0 +1,238 @@
+package commandline
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/DENICeG/go-console/v2"
+)
+
+const (
+	// CompleteCommandName denotes the hidden command used by shell completion scripts to query completion options.
+	CompleteCommandName = "__complete"
+	// CompletionScriptCommandName denotes the hidden command that prints a completion script for the shell given as argument.
+	CompletionScriptCommandName = "__completion"
+)
+
+// Exit codes returned by RunArgs.
+const (
+	ExitCodeSuccess = 0
+	ExitCodeError   = 1
+	ExitCodeUsage   = 2
+)
+
+// ExitCoder is implemented by errors that request a specific exit code from RunArgs.
+type ExitCoder interface {
+	error
+	ExitCode() int
+}
+
+// RunArgs executes a single command given as program arguments, e.g. os.Args[1:], and returns the exit code for os.Exit.
+//
+// Errors are passed to ErrorHandler. The exit code is 0 on success or ErrExit, 2 for usage errors and unknown commands and 1 for all other errors. Errors implementing ExitCoder define their own exit code.
+// Unknown commands are still passed to ExecUnknownCommand.
+// The hidden commands __complete and __completion are used for shell completion as generated by GenerateCompletionScript.
+func (b *Environment) RunArgs(args []string) int {
+	if len(args) == 0 {
+		console.Println("missing command") //nolint
+		return ExitCodeUsage
+	}
+
+	switch args[0] {
+	case CompleteCommandName:
+		return b.runComplete(args[1:])
+	case CompletionScriptCommandName:
+		if len(args) != 3 {
+			console.Printlnf("Usage: %s <bash|zsh|fish> <program>", CompletionScriptCommandName) //nolint
+			return ExitCodeUsage
+		}
+		if err := GenerateCompletionScript(&outputWriter{out: console.DefaultOutput}, args[1], args[2]); err != nil {
+			console.Printlnf("ERROR: %s", err.Error()) //nolint
+			return ExitCodeUsage
+		}
+		return ExitCodeSuccess
+	}
+
+	b.onStart()
+	line := GetCommandString(args)
+	b.beforeExec(line)
+	err := b.ExecCommand(args[0], args[1:])
+	b.afterExec(line, err)
+	if errors.Is(err, ErrExit) {
+		err = nil
+	}
+
+	code := ExitCodeSuccess
+	switch {
+	case err != nil:
+		if b.ErrorHandler != nil {
+			b.ErrorHandler(args[0], args[1:], err)
+		}
+		code = exitCode(err)
+	case !b.isKnownCommand(args[0]):
+		// ExecUnknownCommand has already reported the command
+		code = ExitCodeUsage
+	}
+
+	b.onExit(err)
+	return code
+}
+
+// isKnownCommand returns true if name denotes a registered command or an alias of one.
+func (b *Environment) isKnownCommand(name string) bool {
+	expanded, err := b.expandAliases([]string{name})
+	if err != nil || len(expanded) == 0 {
+		return err == nil
+	}
+	_, exists := b.command(expanded[0])
+	return exists
+}
+
+// exitCode returns the exit code that corresponds to err.
+func exitCode(err error) int {
+	var exitCoder ExitCoder
+	if errors.As(err, &exitCoder) {
+		return exitCoder.ExitCode()
+	}
+	if errors.As(err, &ErrInvalidUsage{}) || errors.As(err, &errUnknownCommand{}) {
+		return ExitCodeUsage
+	}
+	return ExitCodeError
+}
+
+// runComplete prints the completion options for the given command line words. The last word is completed.
+//
+// Every option is printed as replacement and description separated by a tab. The last line is a directive: ":1" if no space should be appended, ":0" otherwise.
+func (b *Environment) runComplete(words []string) int {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+
+	entryIndex := len(words) - 1
+	options := matchOptions(b.GetCompletionOptions(words, entryIndex), words[entryIndex], b.Matcher)
+
+	noSpace := false
+	var sb strings.Builder
+	for _, o := range options {
+		sb.WriteString(o.Replacement())
+		if description := getDescription(o); len(description) > 0 {
+			sb.WriteString("\t" + description)
+		}
+		sb.WriteString("\n")
+		noSpace = noSpace || o.IsPartial()
+	}
+	if noSpace {
+		sb.WriteString(":1\n")
+	} else {
+		sb.WriteString(":0\n")
+	}
+
+	if _, err := console.Print(sb.String()); err != nil {
+		return ExitCodeError
+	}
+	return ExitCodeSuccess
+}
+
+// GenerateCompletionScript writes a completion script for bash, zsh or fish that queries completion options from program using the hidden __complete command of RunArgs.
+//
+// program is used to invoke the tool from the script and may be a path. Completion is registered for its base name, which also covers invocations by path.
+//
+// Typical usage in ~/.bashrc: eval "$(mytool __completion bash mytool)"
+func GenerateCompletionScript(w io.Writer, shell, program string) error {
+	var template string
+	switch shell {
+	case "bash":
+		template = bashCompletionTemplate
+	case "zsh":
+		template = zshCompletionTemplate
+	case "fish":
+		template = fishCompletionTemplate
+	default:
+		return fmt.Errorf("unsupported shell %q", shell)
+	}
+
+	script := strings.NewReplacer(
+		"{{PROGRAM}}", program,
+		"{{NAME}}", programName(program),
+		"{{FUNCTION}}", completionFunctionName(program),
+		"{{COMPLETE}}", CompleteCommandName,
+	).Replace(template)
+	_, err := io.WriteString(w, script)
+	return err
+}
+
+// programName returns the base name of program as typed in a shell.
+func programName(program string) string {
+	if i := strings.LastIndexByte(program, '/'); i >= 0 {
+		return program[i+1:]
+	}
+	return program
+}
+
+// completionFunctionName returns a shell function name for program.
+func completionFunctionName(program string) string {
+	program = programName(program)
+	return "__" + strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, program) + "_complete"
+}
+
+const bashCompletionTemplate = `# bash completion for {{NAME}}
+{{FUNCTION}}() {
+    local IFS=$'\n'
+    local lines line directive
+    lines=($("{{PROGRAM}}" {{COMPLETE}} "${COMP_WORDS[@]:1:$COMP_CWORD}" 2>/dev/null))
+    [[ ${#lines[@]} -eq 0 ]] && return
+    directive=${lines[${#lines[@]}-1]}
+    unset 'lines[${#lines[@]}-1]'
+
+    COMPREPLY=()
+    for line in "${lines[@]}"; do
+        COMPREPLY+=("${line%%$'\t'*}")
+    done
+    if [[ $directive == ":1" ]]; then
+        compopt -o nospace 2>/dev/null
+    fi
+}
+complete -F {{FUNCTION}} {{NAME}}
+`
+
+const zshCompletionTemplate = `#compdef {{NAME}}
+# zsh completion for {{NAME}}
+{{FUNCTION}}() {
+    local -a lines values descriptions
+    local line directive
+    lines=("${(@f)$("{{PROGRAM}}" {{COMPLETE}} "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    [[ ${#lines[@]} -eq 0 ]] && return
+    directive=${lines[-1]}
+
+    for line in "${(@)lines[1,-2]}"; do
+        values+=("${line%%$'\t'*}")
+        if [[ $line == *$'\t'* ]]; then
+            descriptions+=("${line%%$'\t'*}  -- ${line#*$'\t'}")
+        else
+            descriptions+=("${line}")
+        fi
+    done
+    if [[ $directive == ":1" ]]; then
+        compadd -S '' -l -d descriptions -- "${values[@]}"
+    else
+        compadd -l -d descriptions -- "${values[@]}"
+    fi
+}
+compdef {{FUNCTION}} {{NAME}}
+`
+
+const fishCompletionTemplate = `# fish completion for {{NAME}}
+function {{FUNCTION}}
+    set -l tokens (commandline -opc) (commandline -ct)
+    set -e tokens[1]
+    "{{PROGRAM}}" {{COMPLETE}} $tokens 2>/dev/null | string match -v -r '^:[01]$'
+end
+complete -c {{NAME}} -f -a '({{FUNCTION}})'
+`
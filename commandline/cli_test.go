@@ -0,0 +1,85 @@
+package commandline
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/DENICeG/go-console/v2/consoletest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type exitCodeError int
+
+func (e exitCodeError) Error() string { return fmt.Sprintf("exit code %d", int(e)) }
+func (e exitCodeError) ExitCode() int { return int(e) }
+
+func TestRunArgs(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		cle, _, sb := prepareTestCLE()
+		cle.RegisterCommand(NewCustomCommand("fail", nil, func(args []string) error {
+			if len(args) > 0 {
+				return exitCodeError(42)
+			}
+			return fmt.Errorf("failed")
+		}))
+		b := NewCommandBuilder("count")
+		b.IntArg("n", "").Required()
+		cle.RegisterCommand(b.Build(nil))
+		cle.SetAlias("p", "print")
+
+		assert.Equal(t, ExitCodeSuccess, cle.RunArgs([]string{"p", "foo bar"}))
+		assert.Equal(t, ">foo bar<|", sb.String())
+		assert.Equal(t, ExitCodeSuccess, cle.RunArgs([]string{"exit"}))
+
+		assert.Equal(t, ExitCodeError, cle.RunArgs([]string{"fail"}))
+		assert.Contains(t, output.String(), "ERROR: failed\n")
+		assert.Equal(t, 42, cle.RunArgs([]string{"fail", "code"}))
+
+		output.Reset()
+		assert.Equal(t, ExitCodeUsage, cle.RunArgs([]string{"count", "many"}))
+		assert.Contains(t, output.String(), "Usage: count <n>")
+
+		output.Reset()
+		assert.Equal(t, ExitCodeUsage, cle.RunArgs([]string{"unknown"}))
+		assert.Equal(t, "Unknown command \"unknown\"\n", output.String())
+		assert.Equal(t, ExitCodeUsage, cle.RunArgs(nil))
+	})
+}
+
+func TestRunArgsComplete(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		cle, lastCompletionIndex, _ := prepareTestCLE()
+		cle.RegisterCommand(Describe(NewExitCommand("quit"), CommandInfo{Description: "leave"}))
+
+		assert.Equal(t, ExitCodeSuccess, cle.RunArgs([]string{CompleteCommandName, ""}))
+		assert.Equal(t, "exit\nprint\nquit\tleave\n:0\n", output.String())
+
+		output.Reset()
+		assert.Equal(t, ExitCodeSuccess, cle.RunArgs([]string{CompleteCommandName, "print", "foo", "p"}))
+		assert.Equal(t, 2, *lastCompletionIndex)
+		assert.Equal(t, "part\n:1\n", output.String())
+	})
+}
+
+func TestGenerateCompletionScript(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		var sb strings.Builder
+		require.NoError(t, GenerateCompletionScript(&sb, shell, "/usr/bin/zone-tool"))
+		assert.Contains(t, sb.String(), `"/usr/bin/zone-tool" __complete`)
+		assert.Contains(t, sb.String(), "__zone_tool_complete")
+		// completion is registered for the name as typed
+		assert.Regexp(t, `(complete|compdef) .*zone-tool`, sb.String())
+		assert.NotRegexp(t, `(complete|compdef) .*/usr/bin/zone-tool`, sb.String())
+		assert.NotContains(t, sb.String(), "{{")
+	}
+
+	assert.EqualError(t, GenerateCompletionScript(&strings.Builder{}, "tcsh", "tool"), `unsupported shell "tcsh"`)
+
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		assert.Equal(t, ExitCodeSuccess, NewEnvironment().RunArgs([]string{CompletionScriptCommandName, "bash", "tool"}))
+		assert.True(t, strings.HasPrefix(output.String(), "# bash completion for tool\n"))
+	})
+}
@@ -1,70 +1,181 @@
 package commandline
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"sort"
+	"io"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/DENICeG/go-console/v2"
 )
 
 const (
 	maxAutoPrintListLen = 100
-)
-
-var (
-	// remember the last time Tab was pressed to detect double-tab.
-	lastTabPress = time.Unix(0, 0)
+	listSpaceLen        = 2
 )
 
 const doubleTabSpan = 250 * time.Millisecond
 
+// DefaultPromptSuffix is appended to prompts when no other suffix is configured.
+const DefaultPromptSuffix = "> "
+
 // CommandHistoryHandler describes a function that returns a command from history at the given index.
 //
 // Index 0 denotes the latest command. nil is returned when the number of entries in history is exceeded. The index will never be negative.
 type CommandHistoryHandler func(index int) ([]string, bool)
 
+// LineHistoryHandler describes a function that returns a command line from history as entered like CommandHistoryHandler.
+type LineHistoryHandler func(index int) (string, bool)
+
 // ReadCommandOptions configures options and callbacks for ReadComman.
 type ReadCommandOptions struct {
 	// GetHistoryEntry denotes the handler for reading command history.
 	GetHistoryEntry CommandHistoryHandler
+	// GetHistoryLine denotes the handler for reading command lines from history as entered. It is preferred over GetHistoryEntry.
+	GetHistoryLine LineHistoryHandler
 	// GetCompletionOptions denotes the handler for auto completion.
 	GetCompletionOptions CommandCompletionHandler
+	// GetCompletionOptionsContext denotes the handler for context-aware auto completion. It is preferred over GetCompletionOptions.
+	//
+	// A loading hint is displayed after CompletionHintDelay. The completion is cancelled when a key is pressed during loading or CompletionTimeout is exceeded.
+	GetCompletionOptionsContext ContextCommandCompletionHandler
+	// GetCommandLineCompletion denotes the handler for auto completion that decides whether options are loaded asynchronously like with GetCompletionOptionsContext.
+	// It is preferred over GetCompletionOptions and GetCompletionOptionsContext.
+	GetCommandLineCompletion CommandLineCompletionHandler
+	// CompletionTimeout denotes the timeout for context-aware completion. DefaultCompletionTimeout is used when 0.
+	CompletionTimeout time.Duration
+	// CompletionHintDelay denotes the time to wait before a loading hint is displayed. DefaultCompletionHintDelay is used when 0.
+	CompletionHintDelay time.Duration
 	// PrintOptionsHandler denotes the handler to print options on double-tab.
 	PrintOptionsHandler PrintOptionsHandler
+	// Highlighter denotes the handler for syntax highlighting of the entered command.
+	Highlighter Highlighter
+	// GetSuggestion denotes the handler for suggestions that are displayed behind the cursor and can be accepted with Right, End or Alt+F.
+	// Alt+F is not available on Windows, because the Alt modifier is not reported there.
+	GetSuggestion SuggestionHandler
+	// Matcher decides which completion options match the entered command part. PrefixMatcher is used when nil.
+	Matcher Matcher
+	// MenuSelect enables an interactive menu to select completion options with Tab and the arrow keys.
+	MenuSelect bool
+	// PromptSuffix is appended to the prompt. DefaultPromptSuffix is used when empty.
+	PromptSuffix string
+	// RightPrompt is displayed right-aligned in the first line of the command until the input reaches it.
+	RightPrompt string
+	// Validate denotes the handler that is called when Enter is pressed for a complete command.
+	//
+	// Returning an error keeps the command in the editor and displays the error below it.
+	Validate CommandValidationHandler
+	// ValidateCommandLine denotes the handler that is called with the entered command line like Validate. It is preferred over Validate.
+	ValidateCommandLine CommandLineValidationHandler
+	// DiscardLineOnCtrlC discards the entered command on Ctrl+C and starts over with a new prompt like bash. Otherwise, ErrCtrlC is returned.
+	DiscardLineOnCtrlC bool
 }
 
+// CompletionLoader loads completion options that may take a while, e.g. from a remote service. The context is cancelled when the user continues typing or the completion timeout is exceeded.
+type CompletionLoader func(ctx context.Context) ([]CompletionOption, error)
+
+// CommandLineCompletionHandler describes a function that returns all completion options for the last part of cmd, which has been parsed from line.
+// cmd ends with an empty part if a new command part is started.
+//
+// Options that need to be loaded asynchronously are returned as loader instead, which is called in the background while a loading hint is displayed.
+type CommandLineCompletionHandler func(line string, cmd []string) ([]CompletionOption, CompletionLoader)
+
+// CommandValidationHandler describes a function that validates a complete command. Return ErrInvalidArgument to mark a specific command part.
+type CommandValidationHandler func(cmd []string) error
+
+// CommandLineValidationHandler describes a function that validates a complete command line. Return ErrInvalidArgument with the index of the part as returned by ParseCommand to mark it.
+type CommandLineValidationHandler func(line string) error
+
 // ReadCommand reads a command from console input and offers history, aswell as completion functionality.
+//
+// The prompt may span multiple lines. Only the last line is displayed in front of the input.
 func ReadCommand(prompt string, opts *ReadCommandOptions) ([]string, error) {
+	line, err := ReadCommandLine(prompt, opts)
+	if err != nil {
+		return nil, err
+	}
+	cmd, _ := ParseCommand(line)
+	return cmd, nil
+}
+
+// ReadCommandLine reads a command like ReadCommand, but returns the complete command line as entered. This allows further processing like variable expansion.
+//
+// Commands are read line by line without any editing features if the console is not interactive. io.EOF is returned at the end of input.
+func ReadCommandLine(prompt string, opts *ReadCommandOptions) (string, error) {
 	if opts == nil {
 		opts = &ReadCommandOptions{
 			PrintOptionsHandler: DefaultOptionsPrinter(),
 		}
 	}
 
-	var cmd []string
+	if !console.IsInteractive() {
+		return readPlainCommand()
+	}
+
+	var line string
 	err := console.WithReadKeyContext(func() error {
 		var err error
-		cmd, err = readCommand(prompt, opts)
+		line, err = readCommand(prompt, opts)
 		return err
 	})
-	return cmd, err
+	return line, err
 }
 
-func readCommand(prompt string, opts *ReadCommandOptions) ([]string, error) {
+// readPlainCommand reads a command from non-interactive input without prompt, completion or line editing.
+//
+// io.EOF is only returned if there is no further command.
+func readPlainCommand() (string, error) {
 	var sb strings.Builder
 
 	for {
-		line, err := readCommandLine(&prompt, sb.String(), true, opts)
+		line, err := console.ReadLine()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+		atEOF := err != nil
+		if atEOF && len(line) == 0 {
+			if sb.Len() > 0 {
+				return "", fmt.Errorf("unexpected end of input in unterminated quote")
+			}
+			return "", io.EOF
+		}
+
+		sb.WriteString(line)
+
+		if _, isComplete := ParseCommand(sb.String()); isComplete {
+			return sb.String(), nil
+		}
+		if atEOF {
+			return "", fmt.Errorf("unexpected end of input in unterminated quote")
+		}
+		// line break is part of command
+		sb.WriteRune('\n')
+	}
+}
+
+func readCommand(prompt string, opts *ReadCommandOptions) (string, error) {
+	var sb strings.Builder
+	initialPrompt := prompt
+
+	for {
+		line, err := readCommandLine(&prompt, sb.String(), opts)
+		if errors.Is(err, errDiscardLine) {
+			// start over with the complete prompt
+			sb.Reset()
+			prompt = initialPrompt
+			continue
+		}
 		if err != nil {
-			return nil, err
+			return "", err
 		}
 
 		sb.WriteString(line)
 
-		if cmd, isComplete := ParseCommand(sb.String()); isComplete {
-			return cmd, nil
+		if _, isComplete := ParseCommand(sb.String()); isComplete {
+			return sb.String(), nil
 		}
 
 		// line break is part of command -> append to command because it has been omitted by the line reader
@@ -74,98 +185,275 @@ func readCommand(prompt string, opts *ReadCommandOptions) ([]string, error) {
 	}
 }
 
-func readCommandLine(prompt *string, currentCommand string, escapeHistory bool, opts *ReadCommandOptions) (string, error) {
+func readCommandLine(prompt *string, currentCommand string, opts *ReadCommandOptions) (string, error) {
+	renderer := newLineRenderer("")
 	if prompt != nil {
-		console.Printf("%s> ", *prompt) //nolint
+		suffix := opts.PromptSuffix
+		if len(suffix) == 0 {
+			suffix = DefaultPromptSuffix
+		}
+
+		lines := *prompt
+		if pos := strings.LastIndexByte(lines, '\n'); pos >= 0 {
+			// leading prompt lines are printed once, only the last line is redrawn with the input
+			console.Print(strings.ReplaceAll(lines[:pos], "\n", "\r\n") + "\r\n") //nolint
+			lines = lines[pos+1:]
+		}
+		renderer.prompt = lines + suffix
+	}
+	if len(currentCommand) == 0 {
+		renderer.rightPrompt = opts.RightPrompt
 	}
+	// output of background jobs is printed above the edited line
+	setActiveRenderer(renderer)
+	defer setActiveRenderer(nil)
+
+	getHistoryLine := opts.GetHistoryLine
+	if getHistoryLine == nil && opts.GetHistoryEntry != nil {
+		getHistoryLine = func(index int) (string, bool) {
+			cmd, ok := opts.GetHistoryEntry(index)
+			return GetCommandString(cmd), ok
+		}
+	}
+
+	highlight := func(line string) string {
+		if opts.Highlighter == nil || !console.SupportsColors() {
+			return line
+		}
 
-	var cmdToString func([]string) string
-	if escapeHistory {
-		cmdToString = GetCommandString
-	} else {
-		cmdToString = func(cmd []string) string { return strings.Join(cmd, " ") }
+		// tokens might span multiple lines of the command
+		command := currentCommand + line
+		tokens, _ := TokenizeCommand(command)
+		spans := opts.Highlighter(command, tokens)
+		return applySpans(line, shiftSpans(spans, -len(currentCommand)))
 	}
 
-	var sb strings.Builder
+	line := ""
+	suggestion := ""
+	// message lines are displayed below the line until the next key is pressed
+	var messages []string
+	// menu denotes an open completion menu
+	var menu *completionMenu
+	var menuLine, menuPrefix string
+	var menuWordStart int
+	render := func() {
+		suggestion = ""
+		if opts.GetSuggestion != nil && console.SupportsColors() {
+			if suggestion = opts.GetSuggestion(currentCommand + line); strings.ContainsAny(suggestion, "\r\n") {
+				// suggestions are limited to the current line
+				suggestion = ""
+			}
+		}
 
-	lineLen := 0
+		f := renderFrame{line: highlight(line), below: messages}
+		if menu != nil {
+			f.below = append(f.below, menu.Lines(terminalSize())...)
+		}
+		if len(suggestion) > 0 {
+			f.suffix = styleSuggestion + suggestion + ansiReset
+		}
+		renderer.Render(f)
+	}
+	render()
 
-	putRune := func(r rune) {
-		sb.WriteRune(r)
-		console.Print(string(r)) //nolint
-		lineLen++
+	// finish renders the line without suggestion and moves the cursor below
+	finish := func() {
+		renderer.Render(renderFrame{line: highlight(line)})
+		renderer.NewLine()
 	}
 
 	putString := func(str string) {
-		sb.WriteString(str)
-		console.Print(str) //nolint
-		lineLen += len(str)
+		line += str
+		render()
+	}
+
+	replaceLine := func(newLine string) {
+		line = newLine
+		render()
 	}
 
 	clearLine := func() {
-		sb.Reset()
-		str1 := strings.Repeat("\b", lineLen)
-		str2 := strings.Repeat(" ", lineLen)
-		console.Printf("%s%s%s", str1, str2, str1) //nolint
-		lineLen = 0
+		replaceLine("")
 	}
 
-	replaceLine := func(newLine string) {
-		clearLine()
-		putString(newLine)
+	removeLastChar := func() {
+		if len(line) > 0 {
+			replaceLine(removeLastCluster(line))
+		}
 	}
 
-	reprintLine := func() {
-		if prompt == nil {
-			console.Printf("%s", sb.String()) //nolint
-		} else {
-			console.Printf("%s> %s", *prompt, sb.String()) //nolint
+	historyIndex := -1
+	// remember the last time Tab was pressed to detect double-tab.
+	lastTabPress := time.Unix(0, 0)
+
+	// a key that interrupted loading completion options is processed next
+	var bufferedKey *keyResult
+	nextKey := func() (console.Key, rune, error) {
+		if bufferedKey != nil {
+			result := *bufferedKey
+			bufferedKey = nil
+			return result.key, result.r, result.err
 		}
+		return readKey()
 	}
 
-	removeLastChar := func() {
-		if lineLen > 0 {
-			str := []rune(sb.String())
-			sb.Reset()
-			if len(str) > 0 {
-				sb.WriteString(string(str[:len(str)-1]))
-			}
+	// loadCompletionOptions calls load in the background and returns false if the completion has been cancelled or failed
+	loadCompletionOptions := func(load CompletionLoader) ([]CompletionOption, bool) {
+		timeout := opts.CompletionTimeout
+		if timeout <= 0 {
+			timeout = DefaultCompletionTimeout
+		}
+		hintDelay := opts.CompletionHintDelay
+		if hintDelay <= 0 {
+			hintDelay = DefaultCompletionHintDelay
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
 
-			console.Print("\b \b") //nolint
-			lineLen--
+		type completionResult struct {
+			options []CompletionOption
+			err     error
+		}
+		results := make(chan completionResult, 1)
+		go func() {
+			options, err := load(ctx)
+			results <- completionResult{options, err}
+		}()
+
+		hint := time.NewTimer(hintDelay)
+		defer hint.Stop()
+		// keys are not observed before the hint is displayed so fast completions are not interrupted by typing ahead
+		var keys <-chan keyResult
+
+		for {
+			select {
+			case result := <-results:
+				messages = nil
+				if result.err != nil {
+					if errors.Is(result.err, context.DeadlineExceeded) {
+						result.err = ErrCompletionTimeout
+					}
+					messages = []string{result.err.Error()}
+					render()
+					return nil, false
+				}
+				render()
+				return result.options, true
+
+			case <-hint.C:
+				messages = []string{styleLoading("loading…")}
+				render()
+				keys = readKeyAsync()
+
+			case result := <-keys:
+				// user continues typing -> the key is processed as usual
+				keyReceived()
+				bufferedKey = &result
+				messages = nil
+				render()
+				return nil, false
+
+			case <-ctx.Done():
+				// completion handler ignores the context
+				messages = []string{ErrCompletionTimeout.Error()}
+				render()
+				return nil, false
+			}
 		}
 	}
 
-	historyIndex := -1
+	// getCompletionOptions returns false if the completion has been cancelled or failed
+	getCompletionOptions := func(cmd []string) ([]CompletionOption, bool) {
+		if opts.GetCommandLineCompletion != nil {
+			options, load := opts.GetCommandLineCompletion(currentCommand+line, cmd)
+			if load == nil {
+				return options, true
+			}
+			return loadCompletionOptions(load)
+		}
+		if opts.GetCompletionOptionsContext != nil {
+			return loadCompletionOptions(func(ctx context.Context) ([]CompletionOption, error) {
+				return opts.GetCompletionOptionsContext(ctx, cmd, len(cmd)-1)
+			})
+		}
+		return opts.GetCompletionOptions(cmd, len(cmd)-1), true
+	}
 
 	for {
-		key, r, err := console.ReadKey()
+		key, r, err := nextKey()
 		if err != nil {
 			return "", err
 		}
 
+		if len(messages) > 0 {
+			// any key dismisses displayed messages
+			messages = nil
+			render()
+		}
+
+		if menu != nil {
+			// replaces the completed command part by the selected option
+			selectOption := func(withSpace bool) {
+				option := menu.Selected()
+				line, _ = completeCommandPart(menuLine, menuPrefix, menuWordStart, option.Replacement(), isVarReference(option))
+				if withSpace && !option.IsPartial() {
+					line += " "
+				}
+			}
+
+			if menu.HandleKey(key) {
+				selectOption(false)
+				render()
+				continue
+			}
+
+			switch key {
+			case console.KeyEnter:
+				selectOption(true)
+				menu = nil
+				render()
+				continue
+			case console.KeyEscape:
+				line = menuLine
+				menu = nil
+				render()
+				continue
+			}
+
+			// any other key accepts the selection and is processed as usual
+			menu = nil
+			render()
+		}
+
 		switch key {
 		case console.KeyCtrlC:
-			return "", ErrCtrlC
+			if !opts.DiscardLineOnCtrlC {
+				return "", ErrCtrlC
+			}
+			// discarded line stays visible
+			renderer.Render(renderFrame{line: highlight(line), suffix: "^C"})
+			renderer.NewLine()
+			return "", errDiscardLine
 
 		case console.KeyEscape:
 			clearLine()
 
 		case console.KeyUp:
-			if opts.GetHistoryEntry != nil {
-				if newCmd, ok := opts.GetHistoryEntry(historyIndex + 1); ok {
+			if getHistoryLine != nil {
+				if newLine, ok := getHistoryLine(historyIndex + 1); ok {
 					historyIndex++
-					replaceLine(cmdToString(newCmd))
+					replaceLine(newLine)
 				}
 			}
 		case console.KeyDown:
-			if opts.GetHistoryEntry != nil {
+			if getHistoryLine != nil {
 				if historyIndex >= 0 {
 					historyIndex--
 
 					if historyIndex >= 0 {
-						if newCmd, ok := opts.GetHistoryEntry(historyIndex); ok {
-							replaceLine(cmdToString(newCmd))
+						if newLine, ok := getHistoryLine(historyIndex); ok {
+							replaceLine(newLine)
 						} else {
 							// something seems to have changed -> return to initial state
 							historyIndex = -1
@@ -178,55 +466,74 @@ func readCommandLine(prompt *string, currentCommand string, escapeHistory bool,
 			}
 
 		case console.KeyTab:
-			if opts.GetCompletionOptions != nil {
-				str := sb.String()
-				cmd, _ := ParseCommand(fmt.Sprintf("%s%s", currentCommand, str))
+			if opts.GetCompletionOptions != nil || opts.GetCompletionOptionsContext != nil || opts.GetCommandLineCompletion != nil {
+				cmd, _ := ParseCommand(fmt.Sprintf("%s%s", currentCommand, line))
 
 				if len(cmd) == 0 {
 					// append virtual entry to complete commands
 					cmd = []string{""}
-				} else if str[len(str)-1] == ' ' {
+				} else if strings.HasSuffix(line, " ") {
 					// new command part already started by whitespace, but not recognized as part of command
 					// -> append empty command part for processing
 					cmd = append(cmd, "")
 				}
 
 				prefix := cmd[len(cmd)-1]
-				options := filterOptions(opts.GetCompletionOptions(cmd, len(cmd)-1), prefix)
+				wordStart := len(line)
+				if len(prefix) > 0 {
+					// the entered command part is replaced for matches that do not start with it
+					tokens, _ := TokenizeCommand(currentCommand + line)
+					wordStart = max(tokens[len(tokens)-1].Start-len(currentCommand), -1)
+				}
+
+				options, ok := getCompletionOptions(cmd)
+				if !ok {
+					break
+				}
+
+				options = matchOptions(options, prefix, opts.Matcher)
 				if len(options) > 0 {
-					if time.Since(lastTabPress) < doubleTabSpan {
+					if opts.MenuSelect && len(options) > 1 {
+						menu = newCompletionMenu(options)
+						menuLine, menuPrefix, menuWordStart = line, prefix, wordStart
+						line, _ = completeCommandPart(menuLine, menuPrefix, menuWordStart, menu.Selected().Replacement(), isVarReference(menu.Selected()))
+						render()
+
+					} else if time.Since(lastTabPress) < doubleTabSpan {
 						if opts.PrintOptionsHandler != nil {
 							// double-tab detected -> print options
-							console.Println()
-
-							sort.Slice(options, func(i, j int) bool {
-								return options[i].String() < options[j].String()
-							})
+							finish()
 							opts.PrintOptionsHandler(options)
-							reprintLine()
+							render()
 						}
 						// process next tab as single-press
 						lastTabPress = time.Unix(0, 0)
 
 					} else {
 						if len(options) == 1 {
-							if len(options[0].Replacement()) > 0 {
-								suffix := Escape(options[0].Replacement()[len(prefix):])
-								putString(suffix)
-
+							newLine, ok := completeCommandPart(line, prefix, wordStart, options[0].Replacement(), isVarReference(options[0]))
+							if ok && len(options[0].Replacement()) > 0 {
 								if !options[0].IsPartial() {
-									putRune(' ')
+									newLine += " "
 								}
+								replaceLine(newLine)
 							} else {
 								// nothing changed? start double-tab combo
 								lastTabPress = time.Now()
 							}
 
 						} else {
-							longestCommonPrefix := findLongestCommonPrefix(options)
-							suffix := Escape(longestCommonPrefix[len(prefix):])
-							if len(suffix) > 0 {
-								putString(suffix)
+							longestCommonPrefix := ""
+							if allHavePrefix(options, prefix, false) {
+								longestCommonPrefix = findLongestCommonPrefix(options, false)
+							} else if allHavePrefix(options, prefix, true) {
+								longestCommonPrefix = findLongestCommonPrefix(options, true)
+							}
+
+							// variable references are completed exclusively
+							newLine, ok := completeCommandPart(line, prefix, wordStart, longestCommonPrefix, isVarReference(options[0]))
+							if ok && utf8.RuneCountInString(longestCommonPrefix) > utf8.RuneCountInString(prefix) {
+								replaceLine(newLine)
 							} else {
 								// nothing changed? start double-tab combo
 								lastTabPress = time.Now()
@@ -239,18 +546,44 @@ func readCommandLine(prompt *string, currentCommand string, escapeHistory bool,
 				}
 			}
 
+		case console.KeyRight, console.KeyEnd:
+			if len(suggestion) > 0 {
+				putString(suggestion)
+			}
+
+		case console.KeyAlt:
+			if r == 'f' && len(suggestion) > 0 {
+				putString(nextSuggestedWord(suggestion))
+			}
+
 		case console.KeyEnter:
-			console.Println()
-			return sb.String(), nil
+			if opts.Validate != nil || opts.ValidateCommandLine != nil {
+				if cmd, isComplete := ParseCommand(currentCommand + line); isComplete {
+					var err error
+					if opts.ValidateCommandLine != nil {
+						err = opts.ValidateCommandLine(currentCommand + line)
+					} else {
+						err = opts.Validate(cmd)
+					}
+					if err != nil {
+						messages = validationMessages(renderer, currentCommand, line, err)
+						render()
+						continue
+					}
+				}
+			}
+
+			finish()
+			return line, nil
 
 		case console.KeyBackspace:
 			removeLastChar()
 
 		case console.KeySpace:
-			putRune(' ')
+			putString(" ")
 
 		case 0:
-			putRune(r)
+			putString(string(r))
 
 		default:
 			// ignore unknown special keys
@@ -258,35 +591,139 @@ func readCommandLine(prompt *string, currentCommand string, escapeHistory bool,
 	}
 }
 
-func filterOptions(options []CompletionOption, prefix string) []CompletionOption {
-	if options == nil {
-		return nil
+type keyResult struct {
+	err error
+	r   rune
+	key console.Key
+}
+
+// pendingKey receives the key that is read in the background. While it is set, keys must not be read from the console directly, so readKey returns it first.
+var pendingKey chan keyResult
+
+// readKey returns the next key from the console. A key that is read in the background is returned first.
+func readKey() (console.Key, rune, error) {
+	if pendingKey != nil {
+		result := <-pendingKey
+		pendingKey = nil
+		return result.key, result.r, result.err
 	}
+	return console.ReadKey()
+}
 
-	filtered := make([]CompletionOption, 0)
+// readKeyAsync starts reading a single key in the background unless this is already the case. keyReceived needs to be called if the key is received from the returned channel.
+func readKeyAsync() <-chan keyResult {
+	if pendingKey == nil {
+		pendingKey = make(chan keyResult, 1)
+		go func(result chan<- keyResult) {
+			key, r, err := console.ReadKey()
+			result <- keyResult{err, r, key}
+		}(pendingKey)
+	}
+	return pendingKey
+}
+
+// keyReceived marks the key that has been read in the background as received.
+func keyReceived() {
+	pendingKey = nil
+}
+
+func styleLoading(str string) string {
+	if console.SupportsColors() {
+		return styleSuggestion + str + ansiReset
+	}
+	return str
+}
+
+// validationMessages returns the lines to display for a validation error, including a marker below the invalid command part.
+func validationMessages(renderer *lineRenderer, currentCommand, line string, err error) []string {
+	style := func(str string) string {
+		if console.SupportsColors() {
+			return styleUnknownCommand + str + ansiReset
+		}
+		return str
+	}
+
+	messages := make([]string, 0, 2)
+
+	var errArg ErrInvalidArgument
+	if errors.As(err, &errArg) {
+		tokens, _ := TokenizeCommand(currentCommand + line)
+		index := 0
+		for _, t := range tokens {
+			if len(t.Value) == 0 {
+				// empty tokens are not part of the parsed command
+				continue
+			}
+
+			if index == errArg.Index {
+				start := t.Start - len(currentCommand)
+				end := t.End - len(currentCommand)
+				if start >= 0 {
+					col := renderer.Column(line[:start])
+					marker := "^" + strings.Repeat("~", max(displayWidth(line[start:end])-1, 0))
+					messages = append(messages, strings.Repeat(" ", col)+style(marker))
+				}
+				break
+			}
+			index++
+		}
+	}
+
+	return append(messages, style(err.Error()))
+}
+
+// completeCommandPart returns line with the last command part completed to replacement. Variable references in replacement are kept if isVarReference is set.
+//
+// The entered prefix is kept if replacement starts with it. Otherwise the command part starting at wordStart is replaced, which is not possible for negative values.
+func completeCommandPart(line, prefix string, wordStart int, replacement string, isVarReference bool) (string, bool) {
+	escape := Escape
+	if isVarReference {
+		escape = escapeKeepVars
+	}
+	if strings.HasPrefix(replacement, prefix) {
+		return line + escape(replacement[len(prefix):]), true
+	}
+	if wordStart < 0 {
+		// command part started in a previous line
+		return line, false
+	}
+	return line[:wordStart] + escape(replacement), true
+}
+
+func allHavePrefix(options []CompletionOption, prefix string, ignoreCase bool) bool {
 	for _, c := range options {
-		if strings.HasPrefix(c.Replacement(), prefix) {
-			filtered = append(filtered, c)
+		if ignoreCase {
+			if !hasPrefixFold(c.Replacement(), prefix) {
+				return false
+			}
+		} else if !strings.HasPrefix(c.Replacement(), prefix) {
+			return false
 		}
 	}
-	return filtered
+	return true
 }
 
-func findLongestCommonPrefix(options []CompletionOption) string {
+func findLongestCommonPrefix(options []CompletionOption, ignoreCase bool) string {
 	if len(options) == 0 {
 		return ""
 	}
 
+	first := []rune(options[0].Replacement())
 	longestCommonPrefix := ""
 	for i := 1; ; i++ {
-		if len(options[0].Replacement()) < i {
+		if len(first) < i {
 			// prefix cannot be any longer
 			return longestCommonPrefix
 		}
 
-		prefix := options[0].Replacement()[:i]
+		prefix := string(first[:i])
 		for _, c := range options {
-			if !strings.HasPrefix(c.Replacement(), prefix) {
+			if ignoreCase {
+				if !hasPrefixFold(c.Replacement(), prefix) {
+					// the next prefix would not be valid for all options
+					return longestCommonPrefix
+				}
+			} else if !strings.HasPrefix(c.Replacement(), prefix) {
 				// the next prefix would not be valid for all options
 				return longestCommonPrefix
 			}
@@ -298,64 +735,16 @@ func findLongestCommonPrefix(options []CompletionOption) string {
 
 // ParseCommand parses a command input with escape sequences, single quotes and double quotes. The return parameter isComplete is false when a quote or escape sequence is not closed.
 func ParseCommand(str string) (parts []string, isComplete bool) {
-	cmd := make([]string, 0)
+	tokens, isComplete := TokenizeCommand(str)
 
-	var sb strings.Builder
-	escape := false
-	doubleQuote := false
-	singleQuote := false
-
-	for _, r := range str {
-		switch {
-		case singleQuote:
-			if r == '\'' {
-				singleQuote = false
-			} else {
-				sb.WriteRune(r)
-			}
-		case doubleQuote:
-			if escape {
-				if r != '\\' && r != '$' && r != '"' {
-					// consume escape character only for actual escape sequences
-					sb.WriteRune('\\')
-				}
-				sb.WriteRune(r)
-				escape = false
-			} else {
-				if r == '"' {
-					doubleQuote = false
-				} else if r == '\\' {
-					escape = true
-				} else {
-					sb.WriteRune(r)
-				}
-			}
-		case escape:
-			sb.WriteRune(r)
-			escape = false
-		default:
-			if r == '\\' {
-				escape = true
-			} else if r == '\'' {
-				singleQuote = true
-			} else if r == '"' {
-				doubleQuote = true
-			} else if r == ' ' {
-				if sb.Len() > 0 {
-					cmd = append(cmd, sb.String())
-					sb.Reset()
-				}
-			} else {
-				sb.WriteRune(r)
-			}
+	cmd := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		if len(t.Value) > 0 {
+			cmd = append(cmd, t.Value)
 		}
 	}
 
-	if len(sb.String()) > 0 {
-		cmd = append(cmd, sb.String())
-	}
-
-	return cmd, (!escape && !singleQuote && !doubleQuote)
+	return cmd, isComplete
 }
 
 // GetCommandString is the inverse function of Parse() and outputs a single string equal to the given command.
@@ -373,23 +762,40 @@ func GetCommandString(cmd []string) string {
 // Quote returns a quoted string if it contains special chars.
 func Quote(str string) string {
 	if NeedQuote(str) {
-		return fmt.Sprintf("\"%s\"", strings.ReplaceAll(strings.ReplaceAll(str, "\\", "\\\\"), "\"", "\\\""))
+		str = strings.ReplaceAll(str, "\\", "\\\\")
+		str = strings.ReplaceAll(str, "\"", "\\\"")
+		// variables are expanded in double quotes
+		str = strings.ReplaceAll(str, "$", "\\$")
+		return fmt.Sprintf("\"%s\"", str)
 	}
 
 	return str
 }
 
-// NeedQuote returns true when the string contains characters that need to be quoted or escaped.
+// NeedQuote returns true when the string contains characters that need to be quoted or escaped, like spaces, quotes, operators, variable references or wildcards.
 func NeedQuote(str string) bool {
-	return strings.Contains(str, " ")
+	return strings.ContainsAny(str, " \"'\\|&;<>#$*?[")
 }
 
 // Escape returns a string that escapes all special chars.
 func Escape(str string) string {
+	return strings.ReplaceAll(escapeKeepVars(str), "$", "\\$")
+}
+
+// escapeKeepVars escapes all special chars like Escape, but keeps variable references.
+func escapeKeepVars(str string) string {
 	str = strings.ReplaceAll(str, "\\", "\\\\")
 	str = strings.ReplaceAll(str, "\"", "\\\"")
 	str = strings.ReplaceAll(str, "'", "\\'")
 	str = strings.ReplaceAll(str, " ", "\\ ")
+	str = strings.ReplaceAll(str, "*", "\\*")
+	str = strings.ReplaceAll(str, "?", "\\?")
+	str = strings.ReplaceAll(str, "[", "\\[")
+	str = strings.ReplaceAll(str, "|", "\\|")
+	str = strings.ReplaceAll(str, ">", "\\>")
+	str = strings.ReplaceAll(str, "<", "\\<")
+	str = strings.ReplaceAll(str, ";", "\\;")
+	str = strings.ReplaceAll(str, "&", "\\&")
 	str = strings.ReplaceAll(str, "\n", "\\\n")
 	str = strings.ReplaceAll(str, "\r", "\\\r")
 
@@ -418,7 +824,7 @@ func DefaultOptionsPrinter() PrintOptionsHandler {
 		if len(options) > maxAutoPrintListLen {
 			console.Printlnf("  print all %d options? (y/N)", len(options)) //nolint
 			// assume is only called during command reading here (keyboard needs to be prepared)
-			_, r, err := console.ReadKey()
+			_, r, err := readKey()
 			if err != nil {
 				return
 			}
@@ -428,6 +834,49 @@ func DefaultOptionsPrinter() PrintOptionsHandler {
 			}
 		}
 
+		if hasDescriptions(options) {
+			printDescribedOptions(options)
+			return
+		}
+
 		console.PrintList(options) //nolint
 	}
 }
+
+// printDescribedOptions prints one option per line with its dimmed description in a second column that is fitted to the terminal width.
+func printDescribedOptions(options []CompletionOption) {
+	width := terminalWidth()
+
+	labelWidth := 0
+	for _, o := range options {
+		labelWidth = max(labelWidth, displayWidth(o.String()))
+	}
+	if width > 0 {
+		// leave at least half of the line for descriptions
+		labelWidth = min(labelWidth, width/2)
+	}
+
+	var sb strings.Builder
+	for _, o := range options {
+		label := o.String()
+		if width > 0 {
+			label = truncateToWidth(label, labelWidth)
+		}
+		sb.WriteString(label)
+
+		if description := getDescription(o); len(description) > 0 {
+			sb.WriteString(strings.Repeat(" ", labelWidth-displayWidth(label)+listSpaceLen))
+			if width > 0 {
+				// keep the last column free to prevent wrapping
+				description = truncateToWidth(description, width-labelWidth-listSpaceLen-1)
+			}
+			if console.SupportsColors() {
+				description = styleDescription + description + ansiReset
+			}
+			sb.WriteString(description)
+		}
+		sb.WriteString("\n")
+	}
+
+	console.Print(sb.String()) //nolint
+}
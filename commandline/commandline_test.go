@@ -173,6 +173,21 @@ func TestCommandLineEnvironmentLongestPrefixCompletion(t *testing.T) {
 	})
 }
 
+func TestDefaultOptionsPrinterDescriptions(t *testing.T) {
+	consoletest.WithOutputMocks(func(_ *consoletest.MockInput, output *consoletest.MockOutput) {
+		output.Width = 30
+		DefaultOptionsPrinter()([]CompletionOption{
+			NewDescribedCompletionOption("info", "show domain information", false),
+			NewLabelledCompletionOption("UPDATE", "update", false),
+			NewDescribedCompletionOption("delete", "delete", false),
+		})
+
+		assert.Equal(t, "info    "+styleDescription+"show domain informat…"+ansiReset+"\n"+
+			"UPDATE\n"+
+			"delete  "+styleDescription+"delete"+ansiReset+"\n", output.String())
+	})
+}
+
 func prepareTestCLE() (*Environment, *int, *strings.Builder) {
 	var sb strings.Builder
 	var lastCompletionIndex int
@@ -1,5 +1,9 @@
 package commandline
 
+import (
+	"context"
+)
+
 // Command denotes a named command with completion and execution handler.
 type Command interface {
 	// Name returns the name of the command as used in the command line.
@@ -10,11 +14,69 @@ type Command interface {
 	Exec(args []string) error
 }
 
+// ValidatingCommand is implemented by commands that validate their arguments before the command line is accepted.
+type ValidatingCommand interface {
+	Command
+	// Validate is called when Enter is pressed. Return ErrInvalidArgument to mark a specific argument.
+	Validate(args []string) error
+}
+
+// ContextCommand is implemented by commands that can be cancelled, e.g. when running as background job.
+type ContextCommand interface {
+	Command
+	// ExecContext is called instead of Exec to execute the command with a set of arguments. The command should return as soon as ctx is done.
+	ExecContext(ctx context.Context, args []string) error
+}
+
+// ExecContextCommandHandler is called when processing a context command. Return ErrExit to gracefully stop processing.
+type ExecContextCommandHandler func(ctx context.Context, args []string) error
+
+type contextCommand struct {
+	completionHandler CommandCompletionHandler
+	execHandler       ExecContextCommandHandler
+	name              string
+}
+
+// NewContextCommand returns a named command with completion handler that receives a context for cancellation. Exec uses context.Background.
+func NewContextCommand(name string, completionHandler CommandCompletionHandler, execHandler ExecContextCommandHandler) Command {
+	return &contextCommand{
+		name:              name,
+		completionHandler: completionHandler,
+		execHandler:       execHandler,
+	}
+}
+
+func (c *contextCommand) Name() string {
+	return c.name
+}
+
+func (c *contextCommand) GetCompletionOptions(currentCommand []string, entryIndex int) []CompletionOption {
+	if c.completionHandler != nil {
+		return c.completionHandler(currentCommand, entryIndex)
+	}
+	return nil
+}
+
+func (c *contextCommand) Exec(args []string) error {
+	return c.ExecContext(context.Background(), args)
+}
+
+func (c *contextCommand) ExecContext(ctx context.Context, args []string) error {
+	if c.execHandler != nil {
+		return c.execHandler(ctx, args)
+	}
+	return nil
+}
+
 // ExecCommandHandler is called when processing a command. Return ErrExit to gracefully stop processing.
 type ExecCommandHandler func(args []string) error
 
+// ArgsValidationHandler is called to validate the arguments of a command.
+type ArgsValidationHandler func(args []string) error
+
 type customCommand struct {
 	completionHandler CommandCompletionHandler
+	validationHandler ArgsValidationHandler
 	execHandler       ExecCommandHandler
 	name              string
 }
@@ -28,6 +90,12 @@ func (c *customCommand) GetCompletionOptions(currentCommand []string, entryIndex
 	}
 	return nil
 }
+func (c *customCommand) Validate(args []string) error {
+	if c.validationHandler != nil {
+		return c.validationHandler(args)
+	}
+	return nil
+}
 func (c *customCommand) Exec(args []string) error {
 	if c.execHandler != nil {
 		return c.execHandler(args)
@@ -61,3 +129,13 @@ func NewCustomCommand(name string, completionHandler CommandCompletionHandler, e
 		execHandler:       execHandler,
 	}
 }
+
+// NewValidatedCustomCommand returns a named command with completion, validation and execution handler.
+func NewValidatedCustomCommand(name string, completionHandler CommandCompletionHandler, validationHandler ArgsValidationHandler, execHandler ExecCommandHandler) Command {
+	return &customCommand{
+		name:              name,
+		completionHandler: completionHandler,
+		validationHandler: validationHandler,
+		execHandler:       execHandler,
+	}
+}
@@ -0,0 +1,121 @@
+package commandline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCompletionTimeout is used when no other timeout is configured for context-aware completion.
+	DefaultCompletionTimeout = 5 * time.Second
+	// DefaultCompletionHintDelay is used when no other delay is configured to display the loading hint of context-aware completion.
+	DefaultCompletionHintDelay = 200 * time.Millisecond
+)
+
+// ErrCompletionTimeout is displayed when a context-aware completion exceeds its timeout.
+var ErrCompletionTimeout = fmt.Errorf("completion timed out")
+
+// ContextCommandCompletionHandler describes a function that returns all completion options for a given command and entry like CommandCompletionHandler.
+//
+// The context is cancelled when the user continues typing or the completion timeout is exceeded.
+type ContextCommandCompletionHandler func(ctx context.Context, currentCommand []string, entryIndex int) (options []CompletionOption, err error)
+
+// ContextCompletionCommand is implemented by commands that offer context-aware completion. It is preferred over GetCompletionOptions.
+type ContextCompletionCommand interface {
+	Command
+	// GetCompletionOptionsContext denotes a custom completion handler as used for ReadCommand.
+	GetCompletionOptionsContext(ctx context.Context, currentCommand []string, entryIndex int) ([]CompletionOption, error)
+}
+
+// needsContextCompletion returns true if the entry of currentCommand is completed by a command implementing ContextCompletionCommand.
+// Commands wrapped by Describe and command groups, which implement it for all commands, are resolved to the completing command.
+func needsContextCompletion(cmd Command, currentCommand []string, entryIndex int) bool {
+	for {
+		g, ok := unwrapCommand(cmd).(*commandGroup)
+		if !ok {
+			break
+		}
+		if entryIndex < 2 {
+			return false
+		}
+		child, exists := g.children[currentCommand[1]]
+		if !exists {
+			return false
+		}
+		cmd, currentCommand, entryIndex = child, currentCommand[1:], entryIndex-1
+	}
+	_, ok := unwrapCommand(cmd).(ContextCompletionCommand)
+	return ok
+}
+
+type asyncCompletionCommand struct {
+	customCommand
+	contextCompletionHandler ContextCommandCompletionHandler
+}
+
+// NewAsyncCompletionCommand returns a named command with context-aware completion and execution handler.
+func NewAsyncCompletionCommand(name string, completionHandler ContextCommandCompletionHandler, execHandler ExecCommandHandler) Command {
+	return &asyncCompletionCommand{
+		customCommand: customCommand{
+			name:        name,
+			execHandler: execHandler,
+		},
+		contextCompletionHandler: completionHandler,
+	}
+}
+
+func (c *asyncCompletionCommand) GetCompletionOptions(currentCommand []string, entryIndex int) []CompletionOption {
+	options, _ := c.GetCompletionOptionsContext(context.Background(), currentCommand, entryIndex)
+	return options
+}
+
+func (c *asyncCompletionCommand) GetCompletionOptionsContext(ctx context.Context, currentCommand []string, entryIndex int) ([]CompletionOption, error) {
+	if c.contextCompletionHandler != nil {
+		return c.contextCompletionHandler(ctx, currentCommand, entryIndex)
+	}
+	return nil, nil
+}
+
+type cachedCompletionEntry struct {
+	options []CompletionOption
+	expires time.Time
+}
+
+// NewCachedCompletion returns a completion handler that caches the results of handler for the given time to live.
+//
+// Results are cached by the command parts in front of the completed entry, because options are filtered by the entered prefix afterwards. Errors are not cached.
+func NewCachedCompletion(handler ContextCommandCompletionHandler, ttl time.Duration) ContextCommandCompletionHandler {
+	var mutex sync.Mutex
+	cache := make(map[string]cachedCompletionEntry)
+
+	return func(ctx context.Context, currentCommand []string, entryIndex int) ([]CompletionOption, error) {
+		key := GetCommandString(currentCommand[:entryIndex])
+
+		mutex.Lock()
+		entry, exists := cache[key]
+		mutex.Unlock()
+		if exists && time.Now().Before(entry.expires) {
+			return entry.options, nil
+		}
+
+		options, err := handler(ctx, currentCommand, entryIndex)
+		if err != nil {
+			return nil, err
+		}
+
+		mutex.Lock()
+		now := time.Now()
+		for k, e := range cache {
+			// remove expired entries to keep the cache small
+			if now.After(e.expires) {
+				delete(cache, k)
+			}
+		}
+		cache[key] = cachedCompletionEntry{options: options, expires: now.Add(ttl)}
+		mutex.Unlock()
+
+		return options, nil
+	}
+}
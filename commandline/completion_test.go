@@ -0,0 +1,179 @@
+package commandline
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DENICeG/go-console/v2"
+	"github.com/DENICeG/go-console/v2/consoletest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// slowCompletion returns a completion handler that blocks until release is closed or ctx is done.
+func slowCompletion(release <-chan struct{}, cancelled chan<- error) ContextCommandCompletionHandler {
+	return func(ctx context.Context, _ []string, _ int) ([]CompletionOption, error) {
+		select {
+		case <-release:
+			return []CompletionOption{NewCompletionOption("example.com", false), NewCompletionOption("example.de", false)}, nil
+		case <-ctx.Done():
+			cancelled <- ctx.Err()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func TestAsyncCompletion(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		release := make(chan struct{})
+		close(release)
+		env := NewEnvironment()
+		env.RegisterCommand(NewAsyncCompletionCommand("fetch", slowCompletion(release, nil), nil))
+
+		input.PutString("fetch ex\tcom\n")
+		cmd, err := env.ReadCommand()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"fetch", "example.com"}, cmd)
+		input.AssertBufferConsumed(t)
+	})
+}
+
+func TestAsyncCompletionCancelledByTyping(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		release := make(chan struct{})
+		defer close(release)
+		cancelled := make(chan error, 1)
+		env := NewEnvironment()
+		env.CompletionHintDelay = 10 * time.Millisecond
+		env.RegisterCommand(NewAsyncCompletionCommand("fetch", slowCompletion(release, cancelled), nil))
+
+		input.PutString("fetch ex\tample\n")
+		cmd, err := env.ReadCommand()
+		assert.NoError(t, err)
+		// the key pressed during loading is not lost
+		assert.Equal(t, []string{"fetch", "example"}, cmd)
+		input.AssertBufferConsumed(t)
+
+		assert.True(t, strings.Contains(output.String(), "loading…"))
+		assert.ErrorIs(t, <-cancelled, context.Canceled)
+	})
+}
+
+func TestAsyncCompletionTimeout(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		release := make(chan struct{})
+		defer close(release)
+		cancelled := make(chan error, 1)
+
+		input.PutString("fetch ex\t\n")
+		cmd, err := ReadCommand("cle", &ReadCommandOptions{
+			GetCompletionOptionsContext: slowCompletion(release, cancelled),
+			CompletionTimeout:           10 * time.Millisecond,
+			CompletionHintDelay:         time.Hour,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"fetch", "ex"}, cmd)
+		input.AssertBufferConsumed(t)
+
+		assert.True(t, strings.Contains(output.String(), ErrCompletionTimeout.Error()))
+		assert.ErrorIs(t, <-cancelled, context.DeadlineExceeded)
+	})
+}
+
+func TestCompleteCommandLineLoader(t *testing.T) {
+	env := NewEnvironment()
+	env.RegisterCommand(NewAsyncCompletionCommand("fetch", slowCompletion(nil, nil), nil))
+	env.RegisterCommand(Describe(NewCustomCommand("print", NewFixedArgCompletion(NewOneOfArgCompletion("a", "b")), nil), CommandInfo{}))
+	env.RegisterCommand(NewCommandGroup("zone",
+		NewCustomCommand("info", NewFixedArgCompletion(NewOneOfArgCompletion("x")), nil),
+		Describe(NewAsyncCompletionCommand("fetch", slowCompletion(nil, nil), nil), CommandInfo{}),
+	))
+
+	// synchronous completion does not need a loader
+	options, load := env.CompleteCommandLine("print ", []string{"print", ""})
+	assert.Nil(t, load)
+	assert.Equal(t, []string{"a", "b"}, replacements(options))
+	options, load = env.CompleteCommandLine("zone info ", []string{"zone", "info", ""})
+	assert.Nil(t, load)
+	assert.Equal(t, []string{"x"}, replacements(options))
+	_, load = env.CompleteCommandLine("zone ", []string{"zone", ""})
+	assert.Nil(t, load)
+
+	_, load = env.CompleteCommandLine("fetch ", []string{"fetch", ""})
+	assert.NotNil(t, load)
+	_, load = env.CompleteCommandLine("zone fetch ", []string{"zone", "fetch", ""})
+	assert.NotNil(t, load)
+}
+
+func TestReadKeyAfterAsyncRead(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, _ *consoletest.MockOutput) {
+		input.PutString("ab")
+		assert.NoError(t, console.BeginReadKey())
+		defer console.EndReadKey()
+		readKeyAsync()
+		readKeyAsync()
+
+		// the key that is read in the background is returned first
+		_, r, err := readKey()
+		assert.NoError(t, err)
+		assert.Equal(t, 'a', r)
+		_, r, err = readKey()
+		assert.NoError(t, err)
+		assert.Equal(t, 'b', r)
+		input.AssertBufferConsumed(t)
+	})
+}
+
+func TestAsyncCompletionError(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		input.PutString("fetch ex\t\n")
+		cmd, err := ReadCommand("cle", &ReadCommandOptions{
+			GetCompletionOptionsContext: func(_ context.Context, _ []string, _ int) ([]CompletionOption, error) {
+				return nil, errors.New("connection refused")
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"fetch", "ex"}, cmd)
+		input.AssertBufferConsumed(t)
+
+		assert.True(t, strings.Contains(output.String(), "connection refused"))
+	})
+}
+
+func TestCachedCompletion(t *testing.T) {
+	calls := 0
+	handler := NewCachedCompletion(func(_ context.Context, currentCommand []string, entryIndex int) ([]CompletionOption, error) {
+		calls++
+		if currentCommand[0] == "fail" {
+			return nil, errors.New("failed")
+		}
+		return []CompletionOption{NewCompletionOption(currentCommand[0], false)}, nil
+	}, 50*time.Millisecond)
+
+	options, err := handler(context.Background(), []string{"foo", "a"}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"foo"}, replacements(options))
+	// entered prefix is not part of the key
+	_, err = handler(context.Background(), []string{"foo", "ab"}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+
+	_, err = handler(context.Background(), []string{"bar", ""}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	// errors are not cached
+	_, err = handler(context.Background(), []string{"fail", ""}, 1)
+	assert.Error(t, err)
+	_, err = handler(context.Background(), []string{"fail", ""}, 1)
+	assert.Error(t, err)
+	assert.Equal(t, 4, calls)
+
+	time.Sleep(60 * time.Millisecond)
+	_, err = handler(context.Background(), []string{"foo", "a"}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, calls)
+}
@@ -1,7 +1,14 @@
 package commandline
 
 import (
+	"context"
 	"errors"
+	"io"
+	"io/fs"
+	"maps"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/DENICeG/go-console/v2"
 )
@@ -10,18 +17,57 @@ import (
 type Environment struct {
 	history                  CommandHistory
 	Prompt                   PromptHandler
+	PromptSuffix             string
+	RightPrompt              PromptHandler
 	PrintOptions             PrintOptionsHandler
+	Highlighter              Highlighter
+	Suggest                  SuggestionHandler
+	Matcher                  Matcher
 	ExecUnknownCommand       ExecUnknownCommandHandler
 	CompleteUnknownCommand   CommandCompletionHandler
 	ErrorHandler             CommandErrorHandler
 	commands                 map[string]Command
+	aliases                  map[string][]string
+	vars                     map[string]string
 	RecoverPanickedCommands  bool
 	UseCommandNameCompletion bool
+	MenuSelect               bool
+	CompletionTimeout        time.Duration
+	CompletionHintDelay      time.Duration
+	// UseOSEnvironment enables OS environment variables as fallback for undefined session variables.
+	UseOSEnvironment bool
+	// ContinueScriptOnError passes errors of script commands to ErrorHandler and continues with the next command instead of stopping the script.
+	ContinueScriptOnError bool
+	// GlobFS is used for glob expansion instead of the local file system if set.
+	GlobFS fs.FS
+	// GlobNoMatch denotes how glob patterns without matches are handled.
+	GlobNoMatch GlobNoMatchPolicy
+	// CommandTimeout denotes the timeout for all commands unless set by SetCommandTimeout. There is no timeout when 0.
+	CommandTimeout time.Duration
+	// DiscardLineOnCtrlC discards the entered command on Ctrl+C and shows a new prompt. Otherwise, Run returns ErrCtrlC.
+	DiscardLineOnCtrlC bool
+	// OnStart is called when Run, RunScript or RunArgs starts.
+	OnStart func()
+	// BeforePrompt is called by Run before the next command line is read.
+	BeforePrompt func()
+	// BeforeExec is called before a command line of Run, RunScript or RunArgs is executed. Lines of scripts executed by the source command are included.
+	BeforeExec func(line string)
+	// AfterExec is called after a command line of Run, RunScript or RunArgs has been executed with its result. Lines of scripts executed by the source command are included.
+	AfterExec func(line string, err error)
+	// OnExit is called with the result when Run, RunScript or RunArgs returns.
+	OnExit       func(err error)
+	middleware   []Middleware
+	globCommands map[string]bool
+	timeouts     map[string]time.Duration
+	jobs         map[int]*Job
+	jobMutex     sync.Mutex
+	// stateMutex guards commands, aliases, vars, globCommands, timeouts and middleware, which are read by background jobs.
+	stateMutex sync.RWMutex
 }
 
 // NewEnvironment returns a new command line environment.
 func NewEnvironment() *Environment {
-	return &Environment{
+	env := &Environment{
 		Prompt:       func() string { return "cle" },
 		PrintOptions: DefaultOptionsPrinter(),
 		ExecUnknownCommand: func(cmd string, _ []string) error {
@@ -35,13 +81,26 @@ func NewEnvironment() *Environment {
 			} else {
 				console.Printlnf("ERROR: %s", err.Error()) //nolint
 			}
+			var errUsage ErrInvalidUsage
+			if errors.As(err, &errUsage) {
+				console.Print(errUsage.Usage) //nolint
+			}
 			return nil
 		},
 		RecoverPanickedCommands:  true,
 		UseCommandNameCompletion: true,
+		DiscardLineOnCtrlC:       true,
 		history:                  NewCommandHistory(100),
 		commands:                 make(map[string]Command),
+		aliases:                  make(map[string][]string),
+		vars:                     make(map[string]string),
+		globCommands:             make(map[string]bool),
+		timeouts:                 make(map[string]time.Duration),
+		jobs:                     make(map[int]*Job),
 	}
+	env.Highlighter = env.HighlightCommand
+	env.Suggest = NewLineHistorySuggestion(env.historyLine)
+	return env
 }
 
 // SetStaticPrompt sets a constant prompt to display for command input.
@@ -58,11 +117,15 @@ func (b *Environment) prompt() string {
 
 // RegisterCommand adds a new command to the command line environment.
 func (b *Environment) RegisterCommand(cmd Command) {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
 	b.commands[cmd.Name()] = cmd
 }
 
 // UnregisterCommand removes a command from the command line environment and returns true if it was existent before.
 func (b *Environment) UnregisterCommand(commandName string) bool {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
 	_, exists := b.commands[commandName]
 	if exists {
 		delete(b.commands, commandName)
@@ -70,66 +133,159 @@ func (b *Environment) UnregisterCommand(commandName string) bool {
 	return exists
 }
 
-// ReadCommand reads a command for the configured environment.
+// command returns the registered command with the given name.
+func (b *Environment) command(name string) (Command, bool) {
+	b.stateMutex.RLock()
+	defer b.stateMutex.RUnlock()
+	c, exists := b.commands[name]
+	return c, exists
+}
+
+// registeredCommands returns a copy of all registered commands by their names.
+func (b *Environment) registeredCommands() map[string]Command {
+	b.stateMutex.RLock()
+	defer b.stateMutex.RUnlock()
+	return maps.Clone(b.commands)
+}
+
+// ReadCommand reads a command for the configured environment. Variables are expanded in the returned command.
 func (b *Environment) ReadCommand() ([]string, error) {
-	return b.readCommand(ReadCommand)
+	line, err := b.readLine(ReadCommandLine)
+	if err != nil {
+		return nil, err
+	}
+	cmd, _ := b.ParseLine(line)
+	return cmd, nil
 }
 
-func (b *Environment) readCommand(handler func(prompt string, opts *ReadCommandOptions) ([]string, error)) ([]string, error) {
+func (b *Environment) readLine(handler func(prompt string, opts *ReadCommandOptions) (string, error)) (string, error) {
 	opts := &ReadCommandOptions{
-		GetHistoryEntry:      b.history.GetHistoryEntry,
-		GetCompletionOptions: b.GetCompletionOptions,
-		PrintOptionsHandler:  b.PrintOptions,
+		GetHistoryLine:           b.historyLine,
+		GetCommandLineCompletion: b.CompleteCommandLine,
+		CompletionTimeout:        b.CompletionTimeout,
+		CompletionHintDelay:      b.CompletionHintDelay,
+		PrintOptionsHandler:      b.PrintOptions,
+		Highlighter:              b.Highlighter,
+		GetSuggestion:            b.Suggest,
+		ValidateCommandLine:      b.ValidateCommandLine,
+		PromptSuffix:             b.PromptSuffix,
+		MenuSelect:               b.MenuSelect,
+		Matcher:                  b.Matcher,
+		DiscardLineOnCtrlC:       b.DiscardLineOnCtrlC,
+	}
+	if b.RightPrompt != nil {
+		opts.RightPrompt = b.RightPrompt()
 	}
-	cmd, err := handler(b.prompt(), opts)
+	line, err := handler(b.prompt(), opts)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	if len(cmd) > 0 && len(cmd[0]) > 0 {
-		b.history.Put(cmd)
+	// history keeps variable references, quotes and operators
+	if cmd, _ := ParseCommand(line); len(cmd) > 0 && len(cmd[0]) > 0 {
+		b.history.Put(rawCommandParts(line))
 	}
-	return cmd, nil
+	return line, nil
+}
+
+// historyLine returns a command line from history as entered.
+func (b *Environment) historyLine(index int) (string, bool) {
+	parts, ok := b.history.GetHistoryEntry(index)
+	return strings.Join(parts, " "), ok
+}
+
+// rawCommandParts splits a command line like ParseCommand, but keeps quotes and escape sequences.
+func rawCommandParts(line string) []string {
+	tokens, _ := TokenizeCommand(line)
+	parts := make([]string, len(tokens))
+	for i, t := range tokens {
+		parts[i] = line[t.Start:t.End]
+	}
+	return parts
 }
 
 // Run reads and processes commands until an error is returned. Use ErrExit to gracefully stop processing.
+//
+// Commands are read line by line if the console is not interactive, e.g. when Stdin is a pipe. Run returns without error at the end of input.
+//
+// Ctrl+C cancels the context of a running ContextCommand and returns to the prompt. A second Ctrl+C returns without waiting for the command, which is listed as killed job until it returns.
 func (b *Environment) Run() error {
+	b.onStart()
+	err := b.run()
+	b.onExit(err)
+	return err
+}
+
+func (b *Environment) run() error {
+	// output of background jobs is printed above the prompt
+	defer installPromptOutput()()
+
 	for {
-		cmd, err := b.readCommand(ReadCommand)
+		b.beforePrompt()
+		line, err := b.readLine(ReadCommandLine)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
 		if err != nil {
 			return err
 		}
 
-		if len(cmd) > 0 {
-			if err := b.ExecCommand(cmd[0], cmd[1:]); err != nil {
-				if errors.Is(err, ErrExit) {
-					return nil
-				}
-				if b.ErrorHandler == nil {
-					return err
-				}
-
-				b.ErrorHandler(cmd[0], cmd[1:], err)
+		b.beforeExec(line)
+		cmd, err := b.execInterruptible(line)
+		b.afterExec(line, err)
+		if err != nil {
+			if errors.Is(err, ErrExit) {
+				return nil
 			}
+			if errors.Is(err, ErrInterrupted) {
+				// ^C has already been printed
+				continue
+			}
+			if b.ErrorHandler == nil {
+				return err
+			}
+
+			b.ErrorHandler(commandName(cmd), commandArgs(cmd), err)
 		}
 	}
 }
 
-// GetCompletionOptions returns completion options for the given command. This method can be used as callback for ReadCommand.
+// GetCompletionOptions returns completion options for the given command. Operators are not recognized, use CompleteCommandLine for command lines. This method can be used as callback for ReadCommand.
 func (b *Environment) GetCompletionOptions(currentCommand []string, entryIndex int) []CompletionOption {
+	return b.completeCommand(currentCommand, entryIndex, globPatternOf(currentCommand[entryIndex]))
+}
+
+// completeCommand returns completion options for the given command. pattern denotes the glob pattern of the current entry if it contains unquoted wildcards.
+func (b *Environment) completeCommand(currentCommand []string, entryIndex int, pattern string) []CompletionOption {
+	if options, ok := b.varCompletionOptions(currentCommand[entryIndex]); ok && entryIndex > 0 {
+		return options
+	}
+
 	if entryIndex == 0 {
 		if b.UseCommandNameCompletion {
-			// completion for command
-			options := make([]CompletionOption, 0)
-			for name := range b.commands {
-				options = append(options, &completionOption{replacement: name})
+			// completion for command and alias names
+			options := commandNameOptions(b.registeredCommands())
+			for _, o := range b.aliasOptions() {
+				if _, exists := b.command(o.Replacement()); !exists {
+					options = append(options, o)
+				}
 			}
 			return options
 		}
 		return nil
 	}
 
-	cmd, exists := b.commands[currentCommand[0]]
+	// arguments of aliases are completed by the target command
+	currentCommand, entryIndex, ok := b.expandAliasesAt(currentCommand, entryIndex)
+	if !ok || entryIndex < 1 {
+		return nil
+	}
+
+	if options, ok := b.globCompletionOptions(currentCommand, pattern); ok {
+		return options
+	}
+
+	cmd, exists := b.command(currentCommand[0])
 	if !exists {
 		if b.CompleteUnknownCommand != nil {
 			return b.CompleteUnknownCommand(currentCommand, entryIndex)
@@ -140,30 +296,213 @@ func (b *Environment) GetCompletionOptions(currentCommand []string, entryIndex i
 	return cmd.GetCompletionOptions(currentCommand, entryIndex)
 }
 
-// ExecCommand executes a command as if it has been entered in terminal.
+// GetCompletionOptionsContext returns completion options like GetCompletionOptions, but passes ctx to commands implementing ContextCompletionCommand. This method can be used as callback for ReadCommand.
+func (b *Environment) GetCompletionOptionsContext(ctx context.Context, currentCommand []string, entryIndex int) ([]CompletionOption, error) {
+	if load := b.completionLoader(currentCommand, entryIndex, globPatternOf(currentCommand[entryIndex])); load != nil {
+		return load(ctx)
+	}
+	return b.GetCompletionOptions(currentCommand, entryIndex), nil
+}
+
+// CompleteCommandLine returns completion options for the command of the pipeline stage that contains the last part of cmd. Redirection targets are completed by the local file system.
+// Only options of commands implementing ContextCompletionCommand are loaded asynchronously. This method can be used as callback for ReadCommand.
+func (b *Environment) CompleteCommandLine(line string, cmd []string) ([]CompletionOption, CompletionLoader) {
+	stage, stageIndex, isRedirectTarget := stageAt(line, len(cmd)-1)
+	if isRedirectTarget {
+		return NewLocalFileSystemArgCompletion(true).GetCompletionOptions(cmd, len(cmd)-1), nil
+	}
+	if stage.args == nil {
+		return nil, nil
+	}
+
+	// wildcards are only expanded if unquoted
+	pattern := stage.patterns[stageIndex]
+	if load := b.completionLoader(stage.args, stageIndex, pattern); load != nil {
+		return nil, load
+	}
+	return b.completeCommand(stage.args, stageIndex, pattern), nil
+}
+
+// completionLoader returns a loader if the entry is completed by a command implementing ContextCompletionCommand and nil otherwise.
+func (b *Environment) completionLoader(currentCommand []string, entryIndex int, pattern string) CompletionLoader {
+	if entryIndex < 1 {
+		return nil
+	}
+	if _, ok := b.varCompletionOptions(currentCommand[entryIndex]); ok {
+		return nil
+	}
+	expanded, expandedIndex, ok := b.expandAliasesAt(currentCommand, entryIndex)
+	if !ok || expandedIndex < 1 {
+		return nil
+	}
+	if _, ok := b.globCompletionOptions(expanded, pattern); ok {
+		return nil
+	}
+	c, exists := b.command(expanded[0])
+	if !exists || !needsContextCompletion(c, expanded, expandedIndex) {
+		return nil
+	}
+	return func(ctx context.Context) ([]CompletionOption, error) {
+		return c.(ContextCompletionCommand).GetCompletionOptionsContext(ctx, expanded, expandedIndex)
+	}
+}
+
+// ValidateCommandLine checks the syntax of a command line and calls the validator of every command that implements ValidatingCommand. This method can be used as callback for ReadCommandLine.
+//
+// Quoted and escaped operators are part of the arguments and variables are expanded like on execution. The index of ErrInvalidArgument denotes the position in the command as returned by ParseCommand.
+func (b *Environment) ValidateCommandLine(line string) error {
+	items, isComplete := lexCommandLine(line, commandLineOperators, b.expandVars)
+	if !isComplete {
+		return nil
+	}
+
+	for i, item := range items {
+		if len(item.operator) == 0 {
+			continue
+		}
+		isLast := i == len(items)-1
+		switch item.operator {
+		case OperatorRedirectOutput, OperatorAppendOutput, OperatorRedirectInput:
+			if isLast {
+				return NewErrInvalidArgument(item.index, ErrSyntax{}.Error())
+			}
+			if next := items[i+1]; len(next.operator) > 0 {
+				return NewErrInvalidArgument(next.index, ErrSyntax{Operator: next.operator}.Error())
+			}
+		default:
+			if i == 0 {
+				return NewErrInvalidArgument(item.index, ErrSyntax{Operator: item.operator}.Error())
+			}
+			if isLast {
+				if item.operator == OperatorSequence || item.operator == OperatorBackground {
+					// trailing ; and & are allowed
+					break
+				}
+				return NewErrInvalidArgument(item.index, ErrSyntax{}.Error())
+			}
+			if next := items[i+1]; next.operator == OperatorPipe || isListOperator(next.operator) {
+				return NewErrInvalidArgument(next.index, ErrSyntax{Operator: next.operator}.Error())
+			}
+		}
+	}
+
+	// every command of pipelines and command lists is validated separately
+	stages, _ := splitStages(items)
+	for _, s := range stages {
+		err := b.validateStage(s.args)
+		var errArg ErrInvalidArgument
+		if errors.As(err, &errArg) {
+			errArg.Index = items[s.items[min(errArg.Index, len(s.items)-1)]].index
+			return errArg
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateCommand calls the validator of the given command if it implements ValidatingCommand. Operators are not recognized, use ValidateCommandLine for command lines. This method can be used as callback for ReadCommand.
+//
+// Quoting is not known anymore, so variables are expanded in all command parts.
+func (b *Environment) ValidateCommand(cmd []string) error {
+	withVars := make([]string, len(cmd))
+	for i := range cmd {
+		withVars[i] = b.expandVars(cmd[i])
+	}
+	return b.validateStage(withVars)
+}
+
+// validateStage validates a single command whose variables have already been expanded.
+func (b *Environment) validateStage(cmd []string) error {
+	if len(cmd) == 0 {
+		return nil
+	}
+
+	expanded, err := b.expandAliases(cmd)
+	if err != nil {
+		return NewErrInvalidArgument(0, err.Error())
+	}
+	if len(expanded) == 0 {
+		return nil
+	}
+	// number of tokens the alias has been expanded to
+	shift := len(expanded) - len(cmd)
+
+	c, exists := b.command(expanded[0])
+	if !exists {
+		return nil
+	}
+	validator, ok := c.(ValidatingCommand)
+	if !ok {
+		return nil
+	}
+
+	err = validator.Validate(expanded[1:])
+	var errArg ErrInvalidArgument
+	if errors.As(err, &errArg) {
+		// argument index to command line index
+		errArg.Index = max(errArg.Index+1-shift, 0)
+		return errArg
+	}
+	return err
+}
+
+// ExecCommand executes a command as if it has been entered in terminal. Aliases are expanded before the command is dispatched.
 func (b *Environment) ExecCommand(cmd string, args []string) error {
-	var recovered any
+	return b.execStreams(context.Background(), append([]string{cmd}, args...), nil)
+}
+
+// execStreams executes cmd like ExecCommand. Commands implementing StreamCommand are called with streams if not nil, commands implementing ContextCommand are called with ctx.
+func (b *Environment) execStreams(ctx context.Context, cmd []string, streams *Streams) error {
+	expanded, err := b.expandAliases(cmd)
+	if err != nil || len(expanded) == 0 {
+		return err
+	}
+
+	handler := b.chain(func(ctx context.Context, name string, args []string) error {
+		return b.execHandler(ctx, name, args, streams)
+	})
+	return handler(ctx, expanded[0], expanded[1:])
+}
+
+// execHandler executes the named command. It is the innermost handler of the middleware chain.
+func (b *Environment) execHandler(ctx context.Context, name string, args []string, streams *Streams) (err error) {
+	if b.RecoverPanickedCommands {
+		defer func() {
+			// recover from panic and save reason for error handling
+			if recovered := recover(); recovered != nil {
+				err = NewErrCommandPanicked(recovered)
+			}
+		}()
+	}
 
-	err := func() error {
+	if timeout := b.commandTimeout(name); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 		defer func() {
-			if b.RecoverPanickedCommands {
-				// recover from panic and save reason for error handling
-				recovered = recover()
+			if errors.Is(err, context.DeadlineExceeded) && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				err = ErrTimeout{Command: name, Timeout: timeout}
 			}
 		}()
+	}
 
-		// execute command
-		if c, exists := b.commands[cmd]; exists {
-			return c.Exec(args)
+	// execute command
+	if c, exists := b.command(name); exists {
+		leaf := leafCommand(c, args)
+		if _, ok := asStreamCommand(leaf); ok && streams != nil {
+			s, _ := asStreamCommand(c)
+			return s.ExecStreams(args, *streams)
 		}
-		if b.ExecUnknownCommand == nil {
-			return ErrUnknownCommand(cmd)
+		if _, ok := asContextCommand(leaf); ok {
+			cc, _ := asContextCommand(c)
+			return cc.ExecContext(ctx, args)
 		}
-		return b.ExecUnknownCommand(cmd, args)
-	}()
-
-	if recovered != nil {
-		return NewErrCommandPanicked(recovered)
+		return c.Exec(args)
 	}
-	return err
+	if b.ExecUnknownCommand == nil {
+		return ErrUnknownCommand(name)
+	}
+	return b.ExecUnknownCommand(name, args)
 }
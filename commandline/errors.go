@@ -1,7 +1,9 @@
 package commandline
 
 import (
+	"context"
 	"fmt"
+	"time"
 )
 
 // ErrExit is returned when the user wants to exit the application.
@@ -10,6 +12,26 @@ var ErrExit = fmt.Errorf("exit application")
 // ErrCtrlC is returned when the user wants to stop the application.
 var ErrCtrlC = fmt.Errorf("Ctrl+C")
 
+// ErrInterrupted is returned when a running command has been interrupted by Ctrl+C.
+var ErrInterrupted = fmt.Errorf("interrupted")
+
+// errDiscardLine is returned by readCommandLine when Ctrl+C discards the entered command.
+var errDiscardLine = fmt.Errorf("discard line")
+
+// ErrTimeout is returned when a command exceeded its timeout.
+type ErrTimeout struct {
+	Command string
+	Timeout time.Duration
+}
+
+func (e ErrTimeout) Error() string {
+	return fmt.Sprintf("%s timed out after %s", e.Command, e.Timeout)
+}
+
+func (e ErrTimeout) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
 type errUnknownCommand struct {
 	commandName string
 }
@@ -35,3 +57,33 @@ func (e ErrCommandPanicked) Error() string {
 func NewErrCommandPanicked(recovered any) error {
 	return ErrCommandPanicked{recovered}
 }
+
+// ErrInvalidArgument is returned by validators to denote an invalid command part that is marked in the command line.
+type ErrInvalidArgument struct {
+	// Index denotes the invalid command part. For command lines, 0 is the command name. For command validators, 0 is the first argument.
+	Index   int
+	Message string
+}
+
+func (e ErrInvalidArgument) Error() string {
+	return e.Message
+}
+
+// NewErrInvalidArgument returns a new error that indicates an invalid command part at the given index.
+func NewErrInvalidArgument(index int, message string) error {
+	return ErrInvalidArgument{index, message}
+}
+
+// ErrInvalidUsage is returned by commands when the passed arguments do not match the declared parameters.
+type ErrInvalidUsage struct {
+	Err   error
+	Usage string
+}
+
+func (e ErrInvalidUsage) Error() string {
+	return e.Err.Error()
+}
+
+func (e ErrInvalidUsage) Unwrap() error {
+	return e.Err
+}
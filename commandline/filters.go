@@ -0,0 +1,233 @@
+package commandline
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// maxFilterLineLength denotes the maximum length of a line processed by filter commands.
+const maxFilterLineLength = 1024 * 1024
+
+// filterInput returns a reader for the given files or stdin if no file is given.
+func filterInput(files []string, streams Streams) (io.Reader, func(), error) {
+	if len(files) == 0 {
+		return streams.Stdin, func() {}, nil
+	}
+
+	readers := make([]io.Reader, 0, len(files))
+	closers := make([]io.Closer, 0, len(files))
+	closeAll := func() {
+		for _, c := range closers {
+			c.Close() //nolint
+		}
+	}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		readers = append(readers, f)
+		closers = append(closers, f)
+	}
+	return io.MultiReader(readers...), closeAll, nil
+}
+
+// scanLines calls handler for every line of r until handler returns false.
+func scanLines(r io.Reader, handler func(line string) (bool, error)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxFilterLineLength)
+	for scanner.Scan() {
+		proceed, err := handler(scanner.Text())
+		if err != nil || !proceed {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// NewGrepCommand returns a named command that prints all lines of stdin or the given files that match a regular expression.
+func NewGrepCommand(name string) Command {
+	b := NewCommandBuilder(name).
+		Description("print lines matching a pattern").
+		Example(name + " -i pending").
+		Category("Filters")
+	b.BoolFlag("ignore-case", "match case insensitive").Short('i')
+	b.BoolFlag("invert-match", "print lines that do not match").Short('v')
+	b.BoolFlag("count", "print the number of matching lines only").Short('c')
+	b.StringArg("pattern", "regular expression").Required()
+	b.StringArg("file", "files to read instead of stdin").Repeated().WithCompletion(NewLocalFileSystemArgCompletion(true))
+
+	return b.BuildStream(func(args *ParsedArgs, streams Streams) error {
+		pattern := args.String("pattern")
+		if args.Bool("ignore-case") {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %s", err.Error())
+		}
+
+		r, closeInput, err := filterInput(args.Strings("file"), streams)
+		if err != nil {
+			return err
+		}
+		defer closeInput()
+
+		invert := args.Bool("invert-match")
+		count := 0
+		err = scanLines(r, func(line string) (bool, error) {
+			if re.MatchString(line) == invert {
+				return true, nil
+			}
+			count++
+			if args.Bool("count") {
+				return true, nil
+			}
+			_, err := io.WriteString(streams.Stdout, line+"\n")
+			return true, err
+		})
+		if err != nil {
+			return err
+		}
+
+		if args.Bool("count") {
+			_, err = fmt.Fprintln(streams.Stdout, count)
+		}
+		return err
+	})
+}
+
+// NewHeadCommand returns a named command that prints the first lines of stdin or the given files.
+func NewHeadCommand(name string) Command {
+	b := NewCommandBuilder(name).
+		Description("print the first lines").
+		Example(name + " -n 5").
+		Category("Filters")
+	b.IntFlag("lines", "number of lines to print").Short('n').Default("10")
+	b.StringArg("file", "files to read instead of stdin").Repeated().WithCompletion(NewLocalFileSystemArgCompletion(true))
+
+	return b.BuildStream(func(args *ParsedArgs, streams Streams) error {
+		r, closeInput, err := filterInput(args.Strings("file"), streams)
+		if err != nil {
+			return err
+		}
+		defer closeInput()
+
+		remaining := args.Int("lines")
+		if remaining <= 0 {
+			return nil
+		}
+		return scanLines(r, func(line string) (bool, error) {
+			if _, err := io.WriteString(streams.Stdout, line+"\n"); err != nil {
+				return false, err
+			}
+			remaining--
+			return remaining > 0, nil
+		})
+	})
+}
+
+// NewTailCommand returns a named command that prints the last lines of stdin or the given files.
+func NewTailCommand(name string) Command {
+	b := NewCommandBuilder(name).
+		Description("print the last lines").
+		Example(name + " -n 5").
+		Category("Filters")
+	b.IntFlag("lines", "number of lines to print").Short('n').Default("10")
+	b.StringArg("file", "files to read instead of stdin").Repeated().WithCompletion(NewLocalFileSystemArgCompletion(true))
+
+	return b.BuildStream(func(args *ParsedArgs, streams Streams) error {
+		r, closeInput, err := filterInput(args.Strings("file"), streams)
+		if err != nil {
+			return err
+		}
+		defer closeInput()
+
+		count := max(args.Int("lines"), 0)
+		lines := make([]string, 0, count)
+		err = scanLines(r, func(line string) (bool, error) {
+			if count == 0 {
+				return true, nil
+			}
+			if len(lines) == count {
+				lines = lines[1:]
+			}
+			lines = append(lines, line)
+			return true, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, line := range lines {
+			if _, err := io.WriteString(streams.Stdout, line+"\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// NewWcCommand returns a named command that prints the number of lines, words and bytes of stdin or the given files.
+func NewWcCommand(name string) Command {
+	b := NewCommandBuilder(name).
+		Description("print line, word and byte counts").
+		Example(name + " -l").
+		Category("Filters")
+	b.BoolFlag("lines", "print the number of lines").Short('l')
+	b.BoolFlag("words", "print the number of words").Short('w')
+	b.BoolFlag("bytes", "print the number of bytes").Short('c')
+	b.StringArg("file", "files to read instead of stdin").Repeated().WithCompletion(NewLocalFileSystemArgCompletion(true))
+
+	return b.BuildStream(func(args *ParsedArgs, streams Streams) error {
+		r, closeInput, err := filterInput(args.Strings("file"), streams)
+		if err != nil {
+			return err
+		}
+		defer closeInput()
+
+		lines, words, bytes := 0, 0, 0
+		inWord := false
+		reader := bufio.NewReader(r)
+		for {
+			c, size, err := reader.ReadRune()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+
+			bytes += size
+			if c == '\n' {
+				lines++
+			}
+			if unicode.IsSpace(c) {
+				inWord = false
+			} else if !inWord {
+				inWord = true
+				words++
+			}
+		}
+
+		showAll := !args.Bool("lines") && !args.Bool("words") && !args.Bool("bytes")
+		counts := make([]string, 0, 3)
+		if showAll || args.Bool("lines") {
+			counts = append(counts, fmt.Sprint(lines))
+		}
+		if showAll || args.Bool("words") {
+			counts = append(counts, fmt.Sprint(words))
+		}
+		if showAll || args.Bool("bytes") {
+			counts = append(counts, fmt.Sprint(bytes))
+		}
+		_, err = fmt.Fprintln(streams.Stdout, strings.Join(counts, " "))
+		return err
+	})
+}
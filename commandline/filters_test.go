@@ -0,0 +1,57 @@
+package commandline
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func execFilter(t *testing.T, cmd Command, input string, args ...string) string {
+	var sb strings.Builder
+	s, ok := cmd.(StreamCommand)
+	require.True(t, ok)
+	require.NoError(t, s.ExecStreams(args, Streams{Stdin: strings.NewReader(input), Stdout: &sb, Stderr: &sb}))
+	return sb.String()
+}
+
+func TestFilterCommands(t *testing.T) {
+	input := "alpha\nBeta\ngamma\ndelta\nepsilon\n"
+
+	grep := NewGrepCommand("grep")
+	assert.Equal(t, "alpha\ngamma\ndelta\n", execFilter(t, grep, input, "^[a-z].*a$"))
+	assert.Equal(t, "Beta\ndelta\n", execFilter(t, grep, input, "-i", "^[bd]"))
+	assert.Equal(t, "epsilon\n", execFilter(t, grep, input, "-v", "a$"))
+	assert.Equal(t, "4\n", execFilter(t, grep, input, "-c", "a$"))
+	err := grep.(StreamCommand).ExecStreams([]string{"("}, Streams{Stdin: strings.NewReader("")})
+	assert.ErrorContains(t, err, "invalid pattern")
+
+	head := NewHeadCommand("head")
+	assert.Equal(t, "alpha\nBeta\n", execFilter(t, head, input, "-n", "2"))
+	assert.Equal(t, input, execFilter(t, head, input))
+
+	tail := NewTailCommand("tail")
+	assert.Equal(t, "delta\nepsilon\n", execFilter(t, tail, input, "-n", "2"))
+	assert.Equal(t, "", execFilter(t, tail, input, "-n", "0"))
+
+	wc := NewWcCommand("wc")
+	assert.Equal(t, "5 5 31\n", execFilter(t, wc, input))
+	assert.Equal(t, "2 6\n", execFilter(t, wc, "a b\nc d e\nf", "-l", "-w"))
+}
+
+func TestFilterCommandFiles(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	require.NoError(t, os.WriteFile(first, []byte("a.de pending\n"), 0o600))
+	require.NoError(t, os.WriteFile(second, []byte("b.de active\nc.de pending\n"), 0o600))
+
+	assert.Equal(t, "a.de pending\nc.de pending\n", execFilter(t, NewGrepCommand("grep"), "ignored", "pending", first, second))
+	assert.Equal(t, "3\n", execFilter(t, NewWcCommand("wc"), "", "-l", first, second))
+
+	err := NewHeadCommand("head").(StreamCommand).ExecStreams([]string{filepath.Join(dir, "missing.txt")}, Streams{})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
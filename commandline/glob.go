@@ -0,0 +1,288 @@
+package commandline
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// GlobNoMatchPolicy denotes how glob patterns without matches are handled.
+type GlobNoMatchPolicy int
+
+const (
+	// GlobNoMatchKeep passes the pattern as literal argument.
+	GlobNoMatchKeep GlobNoMatchPolicy = iota
+	// GlobNoMatchFail does not execute the command and returns ErrNoGlobMatch.
+	GlobNoMatchFail
+	// GlobNoMatchEmpty removes the argument.
+	GlobNoMatchEmpty
+)
+
+// ErrNoGlobMatch is returned for glob patterns without matches if GlobNoMatch is set to GlobNoMatchFail.
+type ErrNoGlobMatch struct {
+	Pattern string
+}
+
+func (e ErrNoGlobMatch) Error() string {
+	return fmt.Sprintf("no matches found: %s", e.Pattern)
+}
+
+// EnableGlob enables glob expansion for the arguments of the given commands.
+//
+// Unquoted arguments with *, ?, or [...] are replaced by the sorted list of matching paths. ** matches any number of directories. Hidden files are only matched by patterns starting with a dot.
+// Paths are matched against GlobFS or the local file system.
+func (b *Environment) EnableGlob(commandNames ...string) {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+	for _, name := range commandNames {
+		b.globCommands[name] = true
+	}
+}
+
+// DisableGlob disables glob expansion for the given commands.
+func (b *Environment) DisableGlob(commandNames ...string) {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+	for _, name := range commandNames {
+		delete(b.globCommands, name)
+	}
+}
+
+// isGlobEnabled returns true if glob expansion is enabled for the command or the target of the alias with the given name.
+func (b *Environment) isGlobEnabled(name string) bool {
+	if b.globEnabled(name) {
+		return true
+	}
+	expanded, err := b.expandAliases([]string{name})
+	return err == nil && len(expanded) > 0 && b.globEnabled(expanded[0])
+}
+
+// globEnabled returns true if glob expansion has been enabled for the named command.
+func (b *Environment) globEnabled(name string) bool {
+	b.stateMutex.RLock()
+	defer b.stateMutex.RUnlock()
+	return b.globCommands[name]
+}
+
+// expandGlobs returns the commands of p with expanded glob patterns.
+func (b *Environment) expandGlobs(p *Pipeline) (*Pipeline, error) {
+	expanded := &Pipeline{Commands: make([]PipelineCommand, len(p.Commands))}
+	for i, c := range p.Commands {
+		expanded.Commands[i] = c
+		if len(c.Globs) == 0 || !b.isGlobEnabled(c.Args[0]) {
+			continue
+		}
+
+		args := make([]string, 0, len(c.Args))
+		for j, arg := range c.Args {
+			pattern, isGlob := c.Globs[j]
+			if !isGlob || j == 0 {
+				args = append(args, arg)
+				continue
+			}
+
+			matches := b.glob(pattern)
+			if len(matches) > 0 {
+				args = append(args, matches...)
+				continue
+			}
+			switch b.GlobNoMatch {
+			case GlobNoMatchFail:
+				return nil, ErrNoGlobMatch{Pattern: arg}
+			case GlobNoMatchKeep:
+				args = append(args, arg)
+			}
+		}
+		expanded.Commands[i].Args = args
+		expanded.Commands[i].Globs = nil
+	}
+	return expanded, nil
+}
+
+// glob returns all sorted paths that match pattern.
+func (b *Environment) glob(pattern string) []string {
+	if b.GlobFS != nil {
+		return globFS(b.GlobFS, "", pattern)
+	}
+
+	// leading directories without wildcards are opened directly, so absolute paths and .. can be used with os.DirFS
+	base, rest := splitGlobBase(pattern)
+	dir := unescapeGlob(base)
+	if len(dir) == 0 {
+		dir = "."
+	}
+	return globFS(os.DirFS(dir), unescapeGlob(base), rest)
+}
+
+// globFS returns all sorted paths of fsys that match pattern. Every path is prefixed by prefix.
+func globFS(fsys fs.FS, prefix, pattern string) []string {
+	matches := make(map[string]bool)
+
+	var walk func(dir, display string, components []string)
+	walk = func(dir, display string, components []string) {
+		if len(components) == 0 {
+			if len(display) > 0 {
+				matches[display] = true
+			}
+			return
+		}
+		component, rest := components[0], components[1:]
+
+		switch {
+		case component == "**":
+			if len(rest) == 0 {
+				// trailing ** matches all files and directories
+				rest = []string{"*"}
+			}
+			walk(dir, display, rest)
+			entries, _ := fs.ReadDir(fsys, dir)
+			for _, e := range entries {
+				if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+					walk(path.Join(dir, e.Name()), display+e.Name()+"/", components)
+				}
+			}
+
+		case len(component) == 0:
+			// repeated or trailing slash
+			walk(dir, display, rest)
+
+		case !hasGlobMeta(component):
+			name := unescapeGlob(component)
+			p := path.Join(dir, name)
+			if len(rest) == 0 {
+				if _, err := fs.Stat(fsys, p); err == nil {
+					matches[display+name] = true
+				}
+				return
+			}
+			walk(p, display+name+"/", rest)
+
+		default:
+			entries, _ := fs.ReadDir(fsys, dir)
+			for _, e := range entries {
+				name := e.Name()
+				if strings.HasPrefix(name, ".") && !strings.HasPrefix(component, ".") {
+					continue
+				}
+				if ok, _ := path.Match(component, name); !ok {
+					continue
+				}
+				if len(rest) == 0 {
+					matches[display+name] = true
+				} else if isDir(fsys, path.Join(dir, name), e) {
+					walk(path.Join(dir, name), display+name+"/", rest)
+				}
+			}
+		}
+	}
+	walk(".", prefix, strings.Split(pattern, "/"))
+
+	result := make([]string, 0, len(matches))
+	for m := range matches {
+		result = append(result, m)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// isDir returns true if e is a directory or a symbolic link to a directory.
+func isDir(fsys fs.FS, p string, e fs.DirEntry) bool {
+	if e.IsDir() {
+		return true
+	}
+	if e.Type()&fs.ModeSymlink == 0 {
+		return false
+	}
+	info, err := fs.Stat(fsys, p)
+	return err == nil && info.IsDir()
+}
+
+// splitGlobBase splits pattern behind the last slash before the first wildcard.
+func splitGlobBase(pattern string) (string, string) {
+	end := len(pattern)
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '\\' {
+			i++
+		} else if strings.IndexByte("*?[", pattern[i]) >= 0 {
+			end = i
+			break
+		}
+	}
+	index := strings.LastIndexByte(pattern[:end], '/')
+	return pattern[:index+1], pattern[index+1:]
+}
+
+// hasGlobMeta returns true if pattern contains unescaped wildcards.
+func hasGlobMeta(pattern string) bool {
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '\\' {
+			i++
+		} else if strings.IndexByte("*?[", pattern[i]) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// globPatternOf returns entry if it contains wildcards and an empty string otherwise. Quoting is not known anymore, so all wildcards are considered as unquoted.
+func globPatternOf(entry string) string {
+	if hasGlobMeta(entry) {
+		return entry
+	}
+	return ""
+}
+
+// escapeGlob escapes all wildcards in str so it is matched literally.
+func escapeGlob(str string) string {
+	if !strings.ContainsAny(str, `*?[\`) {
+		return str
+	}
+	var sb strings.Builder
+	for _, r := range str {
+		if strings.ContainsRune(`*?[\`, r) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// unescapeGlob removes the escape characters of a pattern without wildcards.
+func unescapeGlob(pattern string) string {
+	if !strings.Contains(pattern, `\`) {
+		return pattern
+	}
+	var sb strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '\\' && i+1 < len(pattern) {
+			i++
+		}
+		sb.WriteByte(pattern[i])
+	}
+	return sb.String()
+}
+
+// globCompletionOption is a completion option for a glob match. It is offered regardless of the entered pattern.
+type globCompletionOption struct {
+	completionOption
+}
+
+// globCompletionOptions returns the matches of pattern if it is not empty and glob expansion is enabled for cmd.
+func (b *Environment) globCompletionOptions(cmd []string, pattern string) ([]CompletionOption, bool) {
+	if len(pattern) == 0 || !b.isGlobEnabled(cmd[0]) {
+		return nil, false
+	}
+	matches := b.glob(pattern)
+	if len(matches) == 0 {
+		return nil, false
+	}
+
+	options := make([]CompletionOption, len(matches))
+	for i, m := range matches {
+		options[i] = &globCompletionOption{completionOption{replacement: m}}
+	}
+	return options, true
+}
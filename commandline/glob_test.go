@@ -0,0 +1,155 @@
+package commandline
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func prepareGlobCLE() (*Environment, *strings.Builder) {
+	cle, _, sb := prepareTestCLE()
+	cle.GlobFS = fstest.MapFS{
+		"a.xml":            {},
+		"b.xml":            {},
+		"c.txt":            {},
+		".hidden.xml":      {},
+		"*.xml":            {},
+		"zones/de/a.zone":  {},
+		"zones/de/b.zone":  {},
+		"zones/com/x.zone": {},
+		"zones/readme.txt": {},
+	}
+	cle.EnableGlob("print")
+	return cle, sb
+}
+
+func TestGlobExpansion(t *testing.T) {
+	cle, sb := prepareGlobCLE()
+
+	for line, expected := range map[string]string{
+		"print *.xml":           ">*.xml<>a.xml<>b.xml<|",
+		"print ?.*":             ">*.xml<>a.xml<>b.xml<>c.txt<|",
+		"print [ab].xml c.txt":  ">a.xml<>b.xml<>c.txt<|",
+		"print .*":              ">.hidden.xml<|",
+		"print zones/*/?.zone":  ">zones/com/x.zone<>zones/de/a.zone<>zones/de/b.zone<|",
+		"print zones/**/*.zone": ">zones/com/x.zone<>zones/de/a.zone<>zones/de/b.zone<|",
+		"print **/*.txt":        ">c.txt<>zones/readme.txt<|",
+		"print zones/**":        ">zones/com<>zones/com/x.zone<>zones/de<>zones/de/a.zone<>zones/de/b.zone<>zones/readme.txt<|",
+		"print zones/*/":        ">zones/com/<>zones/de/<|",
+		// quoted and escaped wildcards are literal
+		`print '*.xml' "?.xml" \*.xml`: ">*.xml<>?.xml<>*.xml<|",
+		`print "*".xml`:                ">*.xml<|",
+		`print "zones/"*/a.zone`:       ">zones/de/a.zone<|",
+	} {
+		sb.Reset()
+		require.NoError(t, cle.ExecLine(line), line)
+		assert.Equal(t, expected, sb.String(), line)
+	}
+
+	// variables are expanded before matching
+	sb.Reset()
+	cle.SetVar("EXT", "txt")
+	require.NoError(t, cle.ExecLine("print *.$EXT"))
+	assert.Equal(t, ">c.txt<|", sb.String())
+
+	// aliases use the setting of their target command
+	sb.Reset()
+	cle.SetAlias("p", "print")
+	require.NoError(t, cle.ExecLine("p c.*"))
+	assert.Equal(t, ">c.txt<|", sb.String())
+
+	// commands without glob expansion get the literal
+	sb.Reset()
+	cle.DisableGlob("print")
+	require.NoError(t, cle.ExecLine("print *.txt"))
+	assert.Equal(t, ">*.txt<|", sb.String())
+}
+
+func TestGlobNoMatchPolicy(t *testing.T) {
+	cle, sb := prepareGlobCLE()
+
+	require.NoError(t, cle.ExecLine("print *.json a"))
+	assert.Equal(t, ">*.json<>a<|", sb.String())
+
+	sb.Reset()
+	cle.GlobNoMatch = GlobNoMatchEmpty
+	require.NoError(t, cle.ExecLine("print *.json a"))
+	assert.Equal(t, ">a<|", sb.String())
+
+	sb.Reset()
+	cle.GlobNoMatch = GlobNoMatchFail
+	err := cle.ExecLine("print *.json a")
+	assert.Equal(t, ErrNoGlobMatch{Pattern: "*.json"}, err)
+	assert.EqualError(t, err, "no matches found: *.json")
+	assert.Empty(t, sb.String())
+}
+
+func TestGlobLocalFileSystem(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub dir"), 0o755))
+	for _, name := range []string{"one.log", "two.log", "sub dir/three.log"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o600))
+	}
+
+	cle, _, sb := prepareTestCLE()
+	cle.EnableGlob("print")
+	cle.SetVar("DIR", dir)
+
+	require.NoError(t, cle.ExecLine(`print "$DIR"/**/*.log`))
+	assert.Equal(t, ">"+dir+"/one.log<>"+dir+"/sub dir/three.log<>"+dir+"/two.log<|", sb.String())
+}
+
+func TestGlobCompletion(t *testing.T) {
+	cle, _ := prepareGlobCLE()
+
+	options := cle.GetCompletionOptions([]string{"print", "zones/*/*.zone"}, 1)
+	assert.Equal(t, []string{"zones/com/x.zone", "zones/de/a.zone", "zones/de/b.zone"}, optionReplacements(options))
+	// matches are not filtered by the pattern
+	assert.Len(t, matchOptions(options, "zones/*/*.zone", nil), 3)
+
+	options, _ = cle.CompleteCommandLine("print zones/*/a.*", []string{"print", "zones/*/a.*"})
+	assert.Equal(t, []string{"zones/de/a.zone"}, optionReplacements(options))
+
+	// quoted and escaped wildcards are not expanded
+	for _, line := range []string{`print "zones/*/*.zone"`, `print zones/\*/*.zone`} {
+		cmd, _ := ParseCommand(line)
+		options, _ = cle.CompleteCommandLine(line, cmd)
+		assert.Equal(t, []string{"foo", "bar", "part"}, optionReplacements(options), line)
+	}
+
+	options = cle.GetCompletionOptions([]string{"print", "*.json"}, 1)
+	assert.NotContains(t, optionReplacements(options), "*.json")
+	assert.Equal(t, []string{"foo", "bar", "part"}, optionReplacements(options))
+}
+
+func TestGlobCompletionInsertsLiteral(t *testing.T) {
+	cle, sb := prepareGlobCLE()
+	cle.GlobFS = fstest.MapFS{
+		"a[1].txt":  {},
+		"a1.txt":    {},
+		"what?.log": {},
+		"whatx.log": {},
+	}
+
+	for line, expected := range map[string]string{
+		"print a?1?.t*": `print a\[1].txt`,
+		`print *\?.log`: `print what\?.log`,
+	} {
+		cmd, _ := ParseCommand(line)
+		options, _ := cle.CompleteCommandLine(line, cmd)
+		require.Len(t, options, 1, line)
+		completed, ok := completeCommandPart(line, cmd[1], len("print "), options[0].Replacement(), false)
+		require.True(t, ok, line)
+		assert.Equal(t, expected, completed, line)
+
+		// the completed argument does not match other files
+		sb.Reset()
+		require.NoError(t, cle.ExecLine(completed), completed)
+		assert.Equal(t, ">"+options[0].Replacement()+"<|", sb.String(), completed)
+	}
+}
@@ -0,0 +1,174 @@
+package commandline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// nestedCommand is implemented by commands that mention their full command path in usage texts.
+type nestedCommand interface {
+	// setParentPath is called when the command is added to a group.
+	setParentPath(path string)
+}
+
+type commandGroup struct {
+	name       string
+	parentPath string
+	children   map[string]Command
+}
+
+// NewCommandGroup returns a named command that dispatches to child commands by the first argument.
+//
+// Groups can be nested to any depth. A usage listing all child commands is returned as ErrInvalidUsage if the child command is missing or unknown.
+func NewCommandGroup(name string, children ...Command) Command {
+	g := &commandGroup{
+		name:     name,
+		children: make(map[string]Command),
+	}
+	for _, c := range children {
+		g.children[c.Name()] = c
+		if nested, ok := c.(nestedCommand); ok {
+			nested.setParentPath(name)
+		}
+	}
+	return g
+}
+
+func (g *commandGroup) Name() string {
+	return g.name
+}
+
+func (g *commandGroup) path() string {
+	if len(g.parentPath) > 0 {
+		return g.parentPath + " " + g.name
+	}
+	return g.name
+}
+
+func (g *commandGroup) setParentPath(path string) {
+	g.parentPath = path
+	for _, c := range g.children {
+		if nested, ok := c.(nestedCommand); ok {
+			nested.setParentPath(g.path())
+		}
+	}
+}
+
+// childNames returns the sorted names of all child commands.
+func (g *commandGroup) childNames() []string {
+	names := make([]string, 0, len(g.children))
+	for name := range g.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (g *commandGroup) child(name string) (Command, bool) {
+	c, exists := g.children[name]
+	return c, exists
+}
+
+func (g *commandGroup) GetCompletionOptions(currentCommand []string, entryIndex int) []CompletionOption {
+	if entryIndex == 1 {
+		return commandNameOptions(g.children)
+	}
+	if child, exists := g.children[currentCommand[1]]; exists && entryIndex > 1 {
+		return child.GetCompletionOptions(currentCommand[1:], entryIndex-1)
+	}
+	return nil
+}
+
+func (g *commandGroup) GetCompletionOptionsContext(ctx context.Context, currentCommand []string, entryIndex int) ([]CompletionOption, error) {
+	if entryIndex > 1 {
+		if child, ok := g.children[currentCommand[1]].(ContextCompletionCommand); ok {
+			return child.GetCompletionOptionsContext(ctx, currentCommand[1:], entryIndex-1)
+		}
+	}
+	return g.GetCompletionOptions(currentCommand, entryIndex), nil
+}
+
+func (g *commandGroup) Validate(args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	validator, ok := g.children[args[0]].(ValidatingCommand)
+	if !ok {
+		return nil
+	}
+
+	err := validator.Validate(args[1:])
+	var errArg ErrInvalidArgument
+	if errors.As(err, &errArg) {
+		// child arguments start behind the child name
+		errArg.Index++
+		return errArg
+	}
+	return err
+}
+
+func (g *commandGroup) Exec(args []string) error {
+	child, err := g.selectChild(args)
+	if err != nil {
+		return err
+	}
+	return child.Exec(args[1:])
+}
+
+// ExecContext passes ctx to the child command if it implements ContextCommand.
+func (g *commandGroup) ExecContext(ctx context.Context, args []string) error {
+	child, err := g.selectChild(args)
+	if err != nil {
+		return err
+	}
+	if c, ok := asContextCommand(child); ok {
+		return c.ExecContext(ctx, args[1:])
+	}
+	return child.Exec(args[1:])
+}
+
+// ExecStreams passes streams to the child command if it implements StreamCommand.
+func (g *commandGroup) ExecStreams(args []string, streams Streams) error {
+	child, err := g.selectChild(args)
+	if err != nil {
+		return err
+	}
+	if s, ok := asStreamCommand(child); ok {
+		return s.ExecStreams(args[1:], streams)
+	}
+	return child.Exec(args[1:])
+}
+
+// selectChild returns the child command denoted by the first argument.
+func (g *commandGroup) selectChild(args []string) (Command, error) {
+	if len(args) == 0 {
+		return nil, ErrInvalidUsage{Err: fmt.Errorf("missing command for %s", g.path()), Usage: g.Usage()}
+	}
+	child, exists := g.children[args[0]]
+	if !exists {
+		return nil, ErrInvalidUsage{Err: fmt.Errorf("unknown command %q for %s", args[0], g.path()), Usage: g.Usage()}
+	}
+	return child, nil
+}
+
+// Usage returns a listing of all child commands.
+func (g *commandGroup) Usage() string {
+	var sb strings.Builder
+	sb.WriteString("Usage: " + g.path() + " <command>\n\nCommands:\n")
+
+	nameWidth := 0
+	for name := range g.children {
+		nameWidth = max(nameWidth, displayWidth(name))
+	}
+	width := terminalWidth()
+	if width <= 0 {
+		width = defaultHelpWidth
+	}
+	for _, name := range g.childNames() {
+		sb.WriteString(describedEntry(name, getCommandDescription(g.children[name]), nameWidth, width))
+	}
+	return sb.String()
+}
@@ -0,0 +1,113 @@
+package commandline
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DENICeG/go-console/v2/consoletest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func prepareCommandGroup(sb *strings.Builder) Command {
+	add := NewCommandBuilder("add")
+	add.EnumArg("type", "record type", "A", "AAAA", "MX").Required()
+	add.StringArg("value", "record value").Required()
+
+	return NewCommandGroup("zone",
+		NewCustomCommand("info", NewFixedArgCompletion(NewOneOfArgCompletion("example.com", "example.de")), newPrintHandler(sb)),
+		NewCommandGroup("record",
+			add.Build(func(args *ParsedArgs) error {
+				sb.WriteString(args.String("type") + "=" + args.String("value"))
+				return nil
+			}),
+			NewCustomCommand("delete", nil, newPrintHandler(sb)),
+		),
+	)
+}
+
+func TestCommandGroupExec(t *testing.T) {
+	sb := &strings.Builder{}
+	g := prepareCommandGroup(sb)
+
+	require.NoError(t, g.Exec([]string{"info", "example.com"}))
+	assert.Equal(t, ">example.com<|", sb.String())
+
+	sb.Reset()
+	require.NoError(t, g.Exec([]string{"record", "add", "MX", "mail"}))
+	assert.Equal(t, "MX=mail", sb.String())
+
+	var errUsage ErrInvalidUsage
+	require.ErrorAs(t, g.Exec(nil), &errUsage)
+	assert.EqualError(t, errUsage, "missing command for zone")
+	assert.Equal(t, "Usage: zone <command>\n\nCommands:\n  info\n  record\n", errUsage.Usage)
+
+	require.ErrorAs(t, g.Exec([]string{"record", "update"}), &errUsage)
+	assert.EqualError(t, errUsage, `unknown command "update" for zone record`)
+	assert.Equal(t, "Usage: zone record <command>\n\nCommands:\n  add\n  delete\n", errUsage.Usage)
+
+	// nested commands know their full path
+	require.ErrorAs(t, g.Exec([]string{"record", "add"}), &errUsage)
+	assert.True(t, strings.HasPrefix(errUsage.Usage, "Usage: zone record add <type> <value>\n"))
+}
+
+func TestCommandGroupCompletion(t *testing.T) {
+	g := prepareCommandGroup(&strings.Builder{})
+
+	assert.Equal(t, []string{"info", "record"}, replacements(g.GetCompletionOptions([]string{"zone", ""}, 1)))
+	assert.Equal(t, []string{"add", "delete"}, replacements(g.GetCompletionOptions([]string{"zone", "record", ""}, 2)))
+	assert.Equal(t, []string{"example.com", "example.de"}, replacements(g.GetCompletionOptions([]string{"zone", "info", ""}, 2)))
+	assert.Equal(t, []string{"A", "AAAA", "MX"}, replacements(g.GetCompletionOptions([]string{"zone", "record", "add", ""}, 3)))
+	assert.Empty(t, g.GetCompletionOptions([]string{"zone", "unknown", ""}, 2))
+}
+
+func TestCommandLineEnvironmentCommandGroup(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		cle, _, sb := prepareTestCLE()
+		cle.RegisterCommand(prepareCommandGroup(sb))
+
+		input.PutString("zo\tre\tad\tM\tfoo\nzone record add TXT\n\r\r\rA bar\nexit\n")
+		assert.NoError(t, cle.Run())
+		input.AssertBufferConsumed(t)
+		assert.Equal(t, "MX=fooA=bar", sb.String())
+
+		// validation errors of nested commands mark the argument in the command line
+		assert.Equal(t, NewErrInvalidArgument(3, `type must be one of A, AAAA, MX, got "TXT"`), cle.ValidateCommand([]string{"zone", "record", "add", "TXT", "foo"}))
+	})
+}
+
+func TestCommandGroupStreamsAndContext(t *testing.T) {
+	cle, _, sb := prepareTestCLE()
+	cle.RegisterCommand(NewWcCommand("wc"))
+	cle.RegisterCommand(Describe(NewCommandGroup("zone",
+		NewStreamCommand("export", nil, func(_ []string, streams Streams) error {
+			_, err := io.WriteString(streams.Stdout, "a\nb\n")
+			return err
+		}),
+		NewContextCommand("check", nil, func(ctx context.Context, _ []string) error {
+			return ctx.Err()
+		}),
+		NewCustomCommand("info", nil, newPrintHandler(sb)),
+	), CommandInfo{}))
+
+	out := filepath.Join(t.TempDir(), "out.txt")
+	require.NoError(t, cle.ExecLine("zone export | wc -l > "+out))
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "2\n", string(data))
+
+	// redirection is only possible if the child command supports streams
+	assert.True(t, cle.supportsStreams([]string{"zone", "export"}))
+	assert.False(t, cle.supportsStreams([]string{"zone", "info"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, cle.execStreams(ctx, []string{"zone", "check"}, nil), context.Canceled)
+	require.NoError(t, cle.execStreams(ctx, []string{"zone", "info", "x"}, nil))
+	assert.Equal(t, ">x<|", sb.String())
+}
@@ -0,0 +1,353 @@
+package commandline
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/DENICeG/go-console/v2"
+)
+
+// defaultHelpWidth is used to wrap help texts when the terminal width is unknown.
+const defaultHelpWidth = 80
+
+// DescribedCommand is implemented by commands with a short description that is displayed in command listings.
+type DescribedCommand interface {
+	Command
+	// Description returns a one line summary of the command.
+	Description() string
+}
+
+// LongHelpCommand is implemented by commands with a detailed explanation that is displayed by the help command.
+type LongHelpCommand interface {
+	Command
+	// Help returns a detailed explanation of the command. Paragraphs are separated by empty lines.
+	Help() string
+}
+
+// UsageCommand is implemented by commands with a usage synopsis.
+type UsageCommand interface {
+	Command
+	// Usage returns a description of the accepted arguments.
+	Usage() string
+}
+
+// ExampleCommand is implemented by commands with example invocations.
+type ExampleCommand interface {
+	Command
+	// Examples returns a list of example command lines.
+	Examples() []string
+}
+
+// CategorizedCommand is implemented by commands that are listed in a category by the help command.
+type CategorizedCommand interface {
+	Command
+	// Category returns the name of the category.
+	Category() string
+}
+
+// CommandInfo contains the help texts of a command as used by Describe.
+type CommandInfo struct {
+	Description string
+	Help        string
+	Usage       string
+	Examples    []string
+	Category    string
+}
+
+type describedCommand struct {
+	Command
+	info CommandInfo
+}
+
+// Describe returns cmd with the given help texts. Empty fields fall back to the texts provided by cmd.
+func Describe(cmd Command, info CommandInfo) Command {
+	return &describedCommand{Command: cmd, info: info}
+}
+
+func (c *describedCommand) Description() string {
+	if len(c.info.Description) == 0 {
+		return getCommandDescription(c.Command)
+	}
+	return c.info.Description
+}
+
+func (c *describedCommand) Help() string {
+	if h, ok := c.Command.(LongHelpCommand); ok && len(c.info.Help) == 0 {
+		return h.Help()
+	}
+	return c.info.Help
+}
+
+func (c *describedCommand) Usage() string {
+	if u, ok := c.Command.(UsageCommand); ok && len(c.info.Usage) == 0 {
+		return u.Usage()
+	}
+	return c.info.Usage
+}
+
+func (c *describedCommand) Examples() []string {
+	if e, ok := c.Command.(ExampleCommand); ok && len(c.info.Examples) == 0 {
+		return e.Examples()
+	}
+	return c.info.Examples
+}
+
+func (c *describedCommand) Category() string {
+	if len(c.info.Category) == 0 {
+		return getCommandCategory(c.Command)
+	}
+	return c.info.Category
+}
+
+func (c *describedCommand) Validate(args []string) error {
+	if v, ok := c.Command.(ValidatingCommand); ok {
+		return v.Validate(args)
+	}
+	return nil
+}
+
+func (c *describedCommand) GetCompletionOptionsContext(ctx context.Context, currentCommand []string, entryIndex int) ([]CompletionOption, error) {
+	if cc, ok := c.Command.(ContextCompletionCommand); ok {
+		return cc.GetCompletionOptionsContext(ctx, currentCommand, entryIndex)
+	}
+	return c.Command.GetCompletionOptions(currentCommand, entryIndex), nil
+}
+
+func (c *describedCommand) setParentPath(path string) {
+	if nested, ok := c.Command.(nestedCommand); ok {
+		nested.setParentPath(path)
+	}
+}
+
+func (c *describedCommand) child(name string) (Command, bool) {
+	if p, ok := c.Command.(parentCommand); ok {
+		return p.child(name)
+	}
+	return nil, false
+}
+
+func (c *describedCommand) childNames() []string {
+	if p, ok := c.Command.(parentCommand); ok {
+		return p.childNames()
+	}
+	return nil
+}
+
+// parentCommand is implemented by commands with child commands like command groups.
+type parentCommand interface {
+	child(name string) (Command, bool)
+	childNames() []string
+}
+
+func getCommandDescription(cmd Command) string {
+	if d, ok := cmd.(DescribedCommand); ok {
+		return d.Description()
+	}
+	return ""
+}
+
+func getCommandCategory(cmd Command) string {
+	if c, ok := cmd.(CategorizedCommand); ok {
+		return c.Category()
+	}
+	return ""
+}
+
+// commandNameOptions returns sorted completion options for the given commands with their descriptions.
+func commandNameOptions(commands map[string]Command) []CompletionOption {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	options := make([]CompletionOption, len(names))
+	for i, name := range names {
+		options[i] = NewDescribedCompletionOption(name, getCommandDescription(commands[name]), false)
+	}
+	return options
+}
+
+// NewHelpCommand returns a named command that lists all commands of env grouped by category or shows the detailed help of a single command.
+func NewHelpCommand(name string, env *Environment) Command {
+	return Describe(&helpCommand{name: name, env: env}, CommandInfo{
+		Description: "show available commands or help for a command",
+		Usage:       fmt.Sprintf("Usage: %s [command] [subcommand...]\n", name),
+	})
+}
+
+type helpCommand struct {
+	name string
+	env  *Environment
+}
+
+func (c *helpCommand) Name() string {
+	return c.name
+}
+
+// resolve returns the (sub-)command denoted by path.
+func (c *helpCommand) resolve(path []string) (Command, error) {
+	cmd, exists := c.env.command(path[0])
+	if !exists {
+		return nil, ErrUnknownCommand(path[0])
+	}
+	for i := 1; i < len(path); i++ {
+		parent, ok := cmd.(parentCommand)
+		if !ok || len(parent.childNames()) == 0 {
+			return nil, fmt.Errorf("%s has no subcommands", strings.Join(path[:i], " "))
+		}
+		if cmd, ok = parent.child(path[i]); !ok {
+			return nil, ErrUnknownCommand(strings.Join(path[:i+1], " "))
+		}
+	}
+	return cmd, nil
+}
+
+func (c *helpCommand) GetCompletionOptions(currentCommand []string, entryIndex int) []CompletionOption {
+	if entryIndex == 1 {
+		return commandNameOptions(c.env.registeredCommands())
+	}
+
+	cmd, err := c.resolve(currentCommand[1:entryIndex])
+	if err != nil {
+		return nil
+	}
+	parent, ok := cmd.(parentCommand)
+	if !ok {
+		return nil
+	}
+	options := make([]CompletionOption, 0)
+	for _, name := range parent.childNames() {
+		child, _ := parent.child(name)
+		options = append(options, NewDescribedCompletionOption(name, getCommandDescription(child), false))
+	}
+	return options
+}
+
+func (c *helpCommand) Exec(args []string) error {
+	width := terminalWidth()
+	if width <= 0 {
+		width = defaultHelpWidth
+	}
+
+	if len(args) == 0 {
+		_, err := console.Print(c.listCommands(width))
+		return err
+	}
+
+	cmd, err := c.resolve(args)
+	if err != nil {
+		return err
+	}
+	_, err = console.Print(commandHelp(cmd, strings.Join(args, " "), width))
+	return err
+}
+
+// listCommands returns all commands grouped by category.
+func (c *helpCommand) listCommands(width int) string {
+	commands := c.env.registeredCommands()
+	categories := make(map[string][]string)
+	nameWidth := 0
+	for name, cmd := range commands {
+		category := getCommandCategory(cmd)
+		categories[category] = append(categories[category], name)
+		nameWidth = max(nameWidth, displayWidth(name))
+	}
+
+	categoryNames := make([]string, 0, len(categories))
+	for category := range categories {
+		categoryNames = append(categoryNames, category)
+	}
+	// uncategorized commands are listed first
+	sort.Strings(categoryNames)
+
+	var sb strings.Builder
+	for i, category := range categoryNames {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		if len(category) == 0 {
+			sb.WriteString("Commands:\n")
+		} else {
+			sb.WriteString(category + ":\n")
+		}
+
+		names := categories[category]
+		sort.Strings(names)
+		for _, name := range names {
+			sb.WriteString(describedEntry(name, getCommandDescription(commands[name]), nameWidth, width))
+		}
+	}
+	return sb.String()
+}
+
+// describedEntry returns an indented list entry with a description that is wrapped to width.
+func describedEntry(name, description string, nameWidth, width int) string {
+	indent := 2 + nameWidth + listSpaceLen
+	if len(description) == 0 {
+		return "  " + name + "\n"
+	}
+	line := "  " + name + strings.Repeat(" ", indent-2-displayWidth(name))
+	return line + wrapText(description, width-indent, strings.Repeat(" ", indent))
+}
+
+// commandHelp returns the detailed help for cmd.
+func commandHelp(cmd Command, path string, width int) string {
+	var sb strings.Builder
+
+	sb.WriteString(path)
+	if description := getCommandDescription(cmd); len(description) > 0 {
+		sb.WriteString(" - " + description)
+	}
+	sb.WriteString("\n")
+
+	if u, ok := cmd.(UsageCommand); ok && len(u.Usage()) > 0 {
+		sb.WriteString("\n" + strings.TrimRight(u.Usage(), "\n") + "\n")
+	}
+
+	if h, ok := cmd.(LongHelpCommand); ok && len(h.Help()) > 0 {
+		sb.WriteString("\n")
+		for i, paragraph := range strings.Split(strings.TrimSpace(h.Help()), "\n\n") {
+			if i > 0 {
+				sb.WriteString("\n")
+			}
+			sb.WriteString(wrapText(strings.Join(strings.Fields(paragraph), " "), width, ""))
+		}
+	}
+
+	if e, ok := cmd.(ExampleCommand); ok && len(e.Examples()) > 0 {
+		sb.WriteString("\nExamples:\n")
+		for _, example := range e.Examples() {
+			sb.WriteString("  " + example + "\n")
+		}
+	}
+
+	return sb.String()
+}
+
+// wrapText breaks text into lines of at most width cells at spaces. All lines but the first are prefixed by indent. The result ends with a line break.
+func wrapText(text string, width int, indent string) string {
+	if width <= 0 {
+		return text + "\n"
+	}
+
+	var sb strings.Builder
+	lineWidth := 0
+	for _, word := range strings.Fields(text) {
+		wordWidth := displayWidth(word)
+		if lineWidth > 0 && lineWidth+1+wordWidth > width {
+			sb.WriteString("\n" + indent)
+			lineWidth = 0
+		}
+		if lineWidth > 0 {
+			sb.WriteString(" ")
+			lineWidth++
+		}
+		sb.WriteString(word)
+		lineWidth += wordWidth
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -0,0 +1,121 @@
+package commandline
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DENICeG/go-console/v2/consoletest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func prepareHelpCLE() *Environment {
+	cle := NewEnvironment()
+	cle.RegisterCommand(NewHelpCommand("help", cle))
+	cle.RegisterCommand(Describe(NewExitCommand("exit"), CommandInfo{Description: "leave the application"}))
+
+	lookup := NewCommandBuilder("lookup").
+		Description("resolve a domain name").
+		Help("Sends a query for the given domain to the configured resolver and prints all records of the answer section.\n\nThe resolver can be changed with the config command.").
+		Example("lookup example.com").
+		Category("DNS")
+	lookup.StringArg("domain", "domain to resolve").Required()
+	cle.RegisterCommand(lookup.Build(nil))
+
+	cle.RegisterCommand(Describe(NewCommandGroup("zone",
+		Describe(NewParameterlessCommand("list", nil), CommandInfo{Description: "list all zones"}),
+		NewParameterlessCommand("reload", nil),
+	), CommandInfo{Description: "manage zones", Category: "DNS"}))
+
+	return cle
+}
+
+func TestWrapText(t *testing.T) {
+	assert.Equal(t, "foo bar\n", wrapText("foo bar", 7, ""))
+	assert.Equal(t, "foo\n  bar\n", wrapText("foo bar", 6, "  "))
+	assert.Equal(t, "foobar\nbaz\n", wrapText("foobar baz", 3, ""))
+	assert.Equal(t, "foo  bar\n", wrapText("foo  bar", 0, ""))
+}
+
+func TestHelpCommandList(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		output.Width = 40
+		cle := prepareHelpCLE()
+
+		require.NoError(t, cle.ExecCommand("help", nil))
+		assert.Equal(t, `Commands:
+  exit    leave the application
+  help    show available commands or
+          help for a command
+
+DNS:
+  lookup  resolve a domain name
+  zone    manage zones
+`, output.String())
+	})
+}
+
+func TestHelpCommandDetails(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		output.Width = 40
+		cle := prepareHelpCLE()
+
+		require.NoError(t, cle.ExecCommand("help", []string{"lookup"}))
+		assert.Equal(t, `lookup - resolve a domain name
+
+Usage: lookup <domain>
+
+Arguments:
+  domain string  domain to resolve (required)
+
+Sends a query for the given domain to
+the configured resolver and prints all
+records of the answer section.
+
+The resolver can be changed with the
+config command.
+
+Examples:
+  lookup example.com
+`, output.String())
+
+		output.Reset()
+		require.NoError(t, cle.ExecCommand("help", []string{"zone"}))
+		assert.Equal(t, "zone - manage zones\n\nUsage: zone <command>\n\nCommands:\n  list    list all zones\n  reload\n", output.String())
+
+		output.Reset()
+		require.NoError(t, cle.ExecCommand("help", []string{"zone", "list"}))
+		assert.Equal(t, "zone list - list all zones\n", output.String())
+
+		assert.EqualError(t, cle.ExecCommand("help", []string{"zone", "drop"}), `unknown command "zone drop"`)
+		assert.EqualError(t, cle.ExecCommand("help", []string{"exit", "now"}), "exit has no subcommands")
+	})
+}
+
+func TestHelpCommandCompletion(t *testing.T) {
+	cle := prepareHelpCLE()
+
+	options := matchOptions(cle.GetCompletionOptions([]string{"help", ""}, 1), "", nil)
+	assert.Equal(t, []string{"exit", "help", "lookup", "zone"}, replacements(options))
+	assert.Equal(t, "leave the application", getDescription(options[0]))
+
+	options = matchOptions(cle.GetCompletionOptions([]string{"help", "zone", ""}, 2), "", nil)
+	assert.Equal(t, []string{"list", "reload"}, replacements(options))
+	assert.Empty(t, cle.GetCompletionOptions([]string{"help", "lookup", ""}, 2))
+
+	// descriptions are also used for command name completion
+	options = matchOptions(cle.GetCompletionOptions([]string{"z"}, 0), "z", nil)
+	assert.Equal(t, "manage zones", getDescription(options[0]))
+}
+
+func TestDescribeForwardsCommand(t *testing.T) {
+	b := NewCommandBuilder("count")
+	b.IntArg("n", "").Required()
+	cmd := Describe(b.Build(nil), CommandInfo{Description: "count to n"}).(*describedCommand)
+
+	assert.Equal(t, NewErrInvalidArgument(0, `n expects an integer, got "x"`), cmd.Validate([]string{"x"}))
+	assert.True(t, strings.HasPrefix(cmd.Usage(), "Usage: count <n>"))
+	assert.Equal(t, "count to n", cmd.Description())
+	assert.Empty(t, cmd.Category())
+}
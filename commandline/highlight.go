@@ -0,0 +1,139 @@
+package commandline
+
+import (
+	"strings"
+)
+
+const (
+	styleKnownCommand   = "\x1b[32m"
+	styleUnknownCommand = "\x1b[31m"
+	styleQuoted         = "\x1b[33m"
+	styleEscaped        = "\x1b[35m"
+	styleFlag           = "\x1b[36m"
+	styleOperator       = "\x1b[1m"
+	styleDim            = "\x1b[2m"
+	styleInverse        = "\x1b[7m"
+
+	// styleSuggestion is used for suggestions and loading hints behind the cursor
+	styleSuggestion = styleDim
+	// styleDescription is used for descriptions of completion options
+	styleDescription = styleDim
+	// styleMenuSelection marks the selected option of the completion menu
+	styleMenuSelection = styleInverse
+	// styleMenuInfo is used for the position info of the completion menu
+	styleMenuInfo = styleDim
+)
+
+// Span denotes a styled range of the command line.
+type Span struct {
+	// Start and End denote the byte offsets in the command line.
+	Start, End int
+	// Style is an ANSI SGR sequence like "\x1b[32m" that is applied to the range.
+	Style string
+}
+
+// Highlighter returns styled spans for a command line. The tokens are the result of TokenizeCommand for line.
+//
+// Highlighting is only applied if the console supports colors.
+type Highlighter func(line string, tokens []Token) []Span
+
+// HighlightCommand is the default highlighter of the environment.
+//
+// It colors registered commands green and unknown commands red. Quoted strings, escape sequences, flags and operators are styled differently.
+func (b *Environment) HighlightCommand(line string, tokens []Token) []Span {
+	spans := make([]Span, 0)
+	for i, t := range tokens {
+		if isOperatorToken(t) {
+			spans = append(spans, Span{Start: t.Start, End: t.End, Style: styleOperator})
+			continue
+		}
+
+		if i == 0 || (isOperatorToken(tokens[i-1]) && (tokens[i-1].Value == OperatorPipe || isListOperator(tokens[i-1].Value))) {
+			style := styleUnknownCommand
+			if _, exists := b.command(t.Value); exists {
+				style = styleKnownCommand
+			} else if _, exists := b.Alias(t.Value); exists {
+				style = styleKnownCommand
+			}
+			spans = append(spans, Span{Start: t.Start, End: t.End, Style: style})
+			continue
+		}
+
+		if strings.HasPrefix(t.Value, "-") && len(t.Segments) > 0 && t.Segments[0].Kind == SegmentPlain {
+			spans = append(spans, Span{Start: t.Start, End: t.End, Style: styleFlag})
+		}
+		spans = append(spans, highlightSegments(t)...)
+	}
+	return spans
+}
+
+// isOperatorToken returns true if t consists of an unquoted operator only.
+func isOperatorToken(t Token) bool {
+	if len(t.Segments) != 1 || t.Segments[0].Kind != SegmentPlain {
+		return false
+	}
+	for _, operator := range commandLineOperators {
+		if t.Value == operator {
+			return true
+		}
+	}
+	return false
+}
+
+func highlightSegments(t Token) []Span {
+	spans := make([]Span, 0)
+	for _, s := range t.Segments {
+		switch s.Kind {
+		case SegmentEscaped:
+			spans = append(spans, Span{Start: s.Start, End: s.End, Style: styleEscaped})
+		case SegmentSingleQuoted, SegmentDoubleQuoted:
+			spans = append(spans, Span{Start: s.Start, End: s.End, Style: styleQuoted})
+		}
+	}
+	return spans
+}
+
+// applySpans returns line with inserted ANSI sequences for all spans. Later spans take precedence over previous ones.
+func applySpans(line string, spans []Span) string {
+	if len(spans) == 0 {
+		return line
+	}
+
+	// determine the style of every single byte
+	styles := make([]string, len(line))
+	for _, s := range spans {
+		for i := max(s.Start, 0); i < min(s.End, len(line)); i++ {
+			styles[i] = s.Style
+		}
+	}
+
+	var sb strings.Builder
+	current := ""
+	for i, r := range line {
+		if styles[i] != current {
+			if len(current) > 0 {
+				sb.WriteString(ansiReset)
+			}
+			sb.WriteString(styles[i])
+			current = styles[i]
+		}
+		sb.WriteRune(r)
+	}
+	if len(current) > 0 {
+		sb.WriteString(ansiReset)
+	}
+	return sb.String()
+}
+
+// shiftSpans moves all spans by offset and drops those that are completely out of range.
+func shiftSpans(spans []Span, offset int) []Span {
+	shifted := make([]Span, 0, len(spans))
+	for _, s := range spans {
+		s.Start += offset
+		s.End += offset
+		if s.End > 0 {
+			shifted = append(shifted, s)
+		}
+	}
+	return shifted
+}
@@ -0,0 +1,61 @@
+package commandline
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DENICeG/go-console/v2/consoletest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplySpans(t *testing.T) {
+	assert.Equal(t, "foo", applySpans("foo", nil))
+	assert.Equal(t, "\x1b[32mfoo\x1b[0m \x1b[33m'b'\x1b[0m", applySpans("foo 'b'", []Span{
+		{Start: 0, End: 3, Style: "\x1b[32m"},
+		{Start: 4, End: 7, Style: "\x1b[33m"},
+	}))
+	// later spans take precedence
+	assert.Equal(t, "\x1b[36m-\x1b[0m\x1b[35m\\x\x1b[0m", applySpans(`-\x`, []Span{
+		{Start: 0, End: 3, Style: "\x1b[36m"},
+		{Start: 1, End: 3, Style: "\x1b[35m"},
+	}))
+}
+
+func TestHighlightCommand(t *testing.T) {
+	cle := NewEnvironment()
+	cle.RegisterCommand(NewExitCommand("exit"))
+
+	line := `exit --now "a\"b"`
+	tokens, _ := TokenizeCommand(line)
+	assert.Equal(t, []Span{
+		{Start: 0, End: 4, Style: styleKnownCommand},
+		{Start: 5, End: 10, Style: styleFlag},
+		{Start: 11, End: 13, Style: styleQuoted},
+		{Start: 13, End: 15, Style: styleEscaped},
+		{Start: 15, End: 17, Style: styleQuoted},
+	}, cle.HighlightCommand(line, tokens))
+
+	tokens, _ = TokenizeCommand("quit")
+	assert.Equal(t, []Span{{Start: 0, End: 4, Style: styleUnknownCommand}}, cle.HighlightCommand("quit", tokens))
+}
+
+func TestReadCommandHighlighting(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		cle := NewEnvironment()
+		cle.RegisterCommand(NewExitCommand("exit"))
+
+		input.PutString("exit\n")
+		_, err := cle.ReadCommand()
+		assert.NoError(t, err)
+		assert.True(t, strings.Contains(output.String(), styleKnownCommand+"exit"+ansiReset))
+
+		output.Reset()
+		output.Colors = false
+		input.PutString("exit\n")
+		_, err = cle.ReadCommand()
+		assert.NoError(t, err)
+		assert.False(t, strings.Contains(output.String(), styleKnownCommand))
+		input.AssertBufferConsumed(t)
+	})
+}
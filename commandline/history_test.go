@@ -1,8 +1,13 @@
 package commandline
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/DENICeG/go-console/v2"
+	"github.com/DENICeG/go-console/v2/consoletest"
+
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -94,6 +99,53 @@ func TestCommandHistoryDeduplication(t *testing.T) {
 	requireHistEntryNil(t, hist, 4)
 }
 
+func TestCommandHistoryRoundTrip(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, _ *consoletest.MockOutput) {
+		cle, _, sb := prepareTestCLE()
+		lines := []string{
+			`print "a;b"`,
+			`print 'x|y'`,
+			`print "&&" ">" '<' "#"`,
+			`print a\&b; print c`,
+			`print '$HOME' \$X`,
+		}
+		input.PutString(strings.Join(lines, "\n") + "\n")
+		// recall the first command line
+		input.PutKeys(console.KeyUp, console.KeyUp, console.KeyUp, console.KeyUp, console.KeyUp, console.KeyEnter)
+		input.PutString("exit\n")
+		require.NoError(t, cle.Run())
+		input.AssertBufferConsumed(t)
+		assert.Equal(t, ">a;b<|>x|y<|>&&<>><><<>#<|>a&b<|>c<|>$HOME<>$X<|>a;b<|", sb.String())
+
+		// the recalled line has moved to the top below exit
+		for i, line := range []string{"exit", lines[0], lines[4], lines[3], lines[2], lines[1]} {
+			recalled, ok := cle.historyLine(i)
+			require.True(t, ok)
+			assert.Equal(t, line, recalled)
+		}
+	})
+
+	// commands are quoted when recalled from a command history
+	cle, _, _ := prepareTestCLE()
+	cle.SetVar("HOME", "/home")
+	hist := NewCommandHistory(1)
+	for _, cmd := range [][]string{{"print", "a;b"}, {"print", "x|y"}, {"print", "&&", ">", "<", "#"}, {"print", "it's"}, {"print", "$HOME", "$X"}} {
+		hist.Put(cmd)
+		entry, _ := hist.GetHistoryEntry(0)
+		recalled, isComplete := ParseCommand(GetCommandString(entry))
+		assert.True(t, isComplete)
+		assert.Equal(t, cmd, recalled)
+		recalled, _ = cle.ParseLine(GetCommandString(entry))
+		assert.Equal(t, cmd, recalled)
+
+		l, err := ParseCommandList(GetCommandString(entry))
+		require.NoError(t, err)
+		require.Len(t, l.Items, 1)
+		require.Len(t, l.Items[0].Pipeline.Commands, 1)
+		assert.Equal(t, cmd, l.Items[0].Pipeline.Commands[0].Args)
+	}
+}
+
 func requireHistEntry(t *testing.T, hist CommandHistory, i int, expected []string) {
 	cmd, ok := hist.GetHistoryEntry(i)
 	require.True(t, ok)
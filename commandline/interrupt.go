@@ -0,0 +1,158 @@
+package commandline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DENICeG/go-console/v2"
+)
+
+// notifyInterrupt and stopInterrupt register c for SIGINT. They are replaced in tests.
+var (
+	notifyInterrupt = func(c chan<- os.Signal) { signal.Notify(c, os.Interrupt) }
+	stopInterrupt   = func(c chan<- os.Signal) { signal.Stop(c) }
+)
+
+// SetCommandTimeout sets the timeout for the named command, which overrides CommandTimeout. A timeout of 0 restores the default.
+//
+// The timeout cancels the context of commands implementing ContextCommand and results in ErrTimeout. Other commands are not affected.
+func (b *Environment) SetCommandTimeout(name string, timeout time.Duration) {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+	if timeout <= 0 {
+		delete(b.timeouts, name)
+		return
+	}
+	b.timeouts[name] = timeout
+}
+
+// commandTimeout returns the timeout for the named command or 0 for none.
+func (b *Environment) commandTimeout(name string) time.Duration {
+	b.stateMutex.RLock()
+	defer b.stateMutex.RUnlock()
+	if timeout, ok := b.timeouts[name]; ok {
+		return timeout
+	}
+	return max(b.CommandTimeout, 0)
+}
+
+type execResult struct {
+	cmd       []string
+	err       error
+	recovered any
+}
+
+// execInterruptible executes a command line like execLine, but cancels the context of the commands on Ctrl+C and prints ^C.
+//
+// A second Ctrl+C stops waiting for the commands, which keep running as killed background job until they return. ErrInterrupted is returned in both cases.
+func (b *Environment) execInterruptible(line string) ([]string, error) {
+	if !console.IsInteractive() {
+		// Ctrl+C stops processing of piped input like before
+		return b.execLine(context.Background(), line)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	notifyInterrupt(signals)
+	defer stopInterrupt(signals)
+
+	// commands might redirect the console output
+	out := &outputWriter{out: console.DefaultOutput}
+	stdout := &switchableWriter{out: out}
+	stdin := &detachableReader{in: &inputReader{}}
+
+	results := make(chan execResult, 1)
+	go func() {
+		var result execResult
+		defer func() {
+			// panics are passed to the caller
+			result.recovered = recover()
+			results <- result
+		}()
+		result.cmd, result.err = b.execLineStreams(ctx, line, Streams{Stdin: stdin, Stdout: stdout, Stderr: stdout})
+	}()
+
+	interrupted := false
+	for {
+		select {
+		case result := <-results:
+			if result.recovered != nil {
+				panic(result.recovered)
+			}
+			if interrupted && (result.err == nil || errors.Is(result.err, context.Canceled)) {
+				return result.cmd, ErrInterrupted
+			}
+			return result.cmd, result.err
+
+		case <-signals:
+			fmt.Fprintln(out, "^C") //nolint
+			if interrupted {
+				// command does not react to cancellation -> abort and keep track of it as killed job, which prints above the prompt and does not read from the console anymore
+				w := &jobWriter{out: out}
+				stdout.Switch(w)
+				stdin.Detach()
+				j := b.addJob(line, cancel, out)
+				j.Kill()
+				go func() {
+					result := <-results
+					if result.recovered != nil {
+						// the caller does not wait anymore
+						result.err = NewErrCommandPanicked(result.recovered)
+					}
+					w.Flush() //nolint
+					b.finishJob(j, result.err, out)
+				}()
+				return nil, ErrInterrupted
+			}
+			interrupted = true
+			cancel()
+		}
+	}
+}
+
+// switchableWriter writes to an output that can be replaced while writing.
+type switchableWriter struct {
+	mutex sync.RWMutex
+	out   io.Writer
+}
+
+func (w *switchableWriter) Write(p []byte) (int, error) {
+	w.mutex.RLock()
+	out := w.out
+	w.mutex.RUnlock()
+	return out.Write(p)
+}
+
+// Switch replaces the output of w.
+func (w *switchableWriter) Switch(out io.Writer) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.out = out
+}
+
+// detachableReader reads from in until it is detached and returns io.EOF afterwards.
+type detachableReader struct {
+	in       io.Reader
+	detached atomic.Bool
+}
+
+func (r *detachableReader) Read(p []byte) (int, error) {
+	if r.detached.Load() {
+		return 0, io.EOF
+	}
+	return r.in.Read(p)
+}
+
+// Detach lets all following reads return io.EOF.
+func (r *detachableReader) Detach() {
+	r.detached.Store(true)
+}
@@ -0,0 +1,212 @@
+package commandline
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DENICeG/go-console/v2"
+	"github.com/DENICeG/go-console/v2/consoletest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withInterruptMock replaces the SIGINT registration. Interrupts are sent by calling the passed function.
+func withInterruptMock(f func(interrupt func())) {
+	oldNotify, oldStop := notifyInterrupt, stopInterrupt
+	defer func() {
+		notifyInterrupt, stopInterrupt = oldNotify, oldStop
+	}()
+
+	registered := make(chan chan<- os.Signal, 1)
+	notifyInterrupt = func(c chan<- os.Signal) { registered <- c }
+	stopInterrupt = func(chan<- os.Signal) {}
+
+	f(func() {
+		c := <-registered
+		c <- os.Interrupt
+		registered <- c
+	})
+}
+
+func TestInterruptContextCommand(t *testing.T) {
+	consoletest.WithOutputMocks(func(_ *consoletest.MockInput, output *consoletest.MockOutput) {
+		withInterruptMock(func(interrupt func()) {
+			cle, _, sb := prepareTestCLE()
+			started := make(chan struct{})
+			cle.RegisterCommand(NewContextCommand("block", nil, func(ctx context.Context, _ []string) error {
+				close(started)
+				<-ctx.Done()
+				return ctx.Err()
+			}))
+
+			go func() {
+				<-started
+				interrupt()
+			}()
+			_, err := cle.execInterruptible("block; print a")
+			assert.Equal(t, ErrInterrupted, err)
+			assert.Equal(t, "^C\n", output.String())
+			// the command list is stopped
+			assert.Empty(t, sb.String())
+		})
+	})
+}
+
+func TestInterruptForceAbort(t *testing.T) {
+	consoletest.WithOutputMocks(func(_ *consoletest.MockInput, output *consoletest.MockOutput) {
+		withInterruptMock(func(interrupt func()) {
+			cle, _, _ := prepareTestCLE()
+			started := make(chan struct{})
+			release := make(chan struct{})
+			cle.RegisterCommand(NewCustomCommand("block", nil, func([]string) error {
+				close(started)
+				<-release
+				_, err := console.Println("late")
+				return err
+			}))
+
+			go func() {
+				<-started
+				interrupt()
+				interrupt()
+			}()
+			_, err := cle.execInterruptible("block")
+			assert.Equal(t, ErrInterrupted, err)
+			assert.Equal(t, "^C\n^C\n[1] block\n", output.String())
+
+			// the aborted command is tracked as job until it returns
+			j, exists := cle.Job(1)
+			require.True(t, exists)
+			assert.Equal(t, JobRunning, j.State())
+			close(release)
+			assert.NoError(t, j.Wait())
+			assert.Equal(t, JobKilled, j.State())
+			assert.Equal(t, "^C\n^C\n[1] block\nlate\n[1] Killed  block\n", output.String())
+			assert.Same(t, output, console.DefaultOutput)
+			assert.Empty(t, cle.Jobs())
+		})
+	})
+}
+
+func TestInterruptForceAbortStreams(t *testing.T) {
+	consoletest.WithOutputMocks(func(_ *consoletest.MockInput, output *consoletest.MockOutput) {
+		withInterruptMock(func(interrupt func()) {
+			restore := installPromptOutput()
+			defer restore()
+
+			cle, _, _ := prepareTestCLE()
+			started := make(chan struct{})
+			release := make(chan struct{})
+			var readErr error
+			cle.RegisterCommand(NewStreamCommand("block", nil, func(_ []string, streams Streams) error {
+				close(started)
+				<-release
+				_, readErr = streams.Stdin.Read(make([]byte, 1))
+				if _, err := io.WriteString(streams.Stdout, "la"); err != nil {
+					return err
+				}
+				_, err := io.WriteString(streams.Stdout, "te\n")
+				return err
+			}))
+
+			go func() {
+				<-started
+				interrupt()
+				interrupt()
+			}()
+			_, err := cle.execInterruptible("block")
+			assert.Equal(t, ErrInterrupted, err)
+			j, exists := cle.Job(1)
+			require.True(t, exists)
+
+			// the next command is read while the aborted command writes its output
+			r := newLineRenderer("cle> ")
+			setActiveRenderer(r)
+			defer setActiveRenderer(nil)
+			r.Render(renderFrame{line: "foo"})
+			close(release)
+			assert.NoError(t, j.Wait())
+
+			assert.Equal(t, io.EOF, readErr)
+			assert.Contains(t, output.String(), ansiClearToEnd+"late\r\n"+ansiClearToEnd+"cle> foo")
+			assert.NotContains(t, output.String(), "la\r\n")
+		})
+	})
+}
+
+func TestInterruptedCommandError(t *testing.T) {
+	consoletest.WithOutputMocks(func(_ *consoletest.MockInput, _ *consoletest.MockOutput) {
+		withInterruptMock(func(interrupt func()) {
+			cle, _, _ := prepareTestCLE()
+			started := make(chan struct{})
+			cle.RegisterCommand(NewContextCommand("cleanup", nil, func(ctx context.Context, _ []string) error {
+				close(started)
+				<-ctx.Done()
+				return errors.New("cleanup failed")
+			}))
+
+			go func() {
+				<-started
+				interrupt()
+			}()
+			// errors other than cancellation are still reported
+			cmd, err := cle.execInterruptible("cleanup")
+			assert.EqualError(t, err, "cleanup failed")
+			assert.Equal(t, []string{"cleanup"}, cmd)
+		})
+	})
+}
+
+func TestCommandTimeout(t *testing.T) {
+	cle, _, sb := prepareTestCLE()
+	cle.RegisterCommand(NewContextCommand("block", nil, func(ctx context.Context, _ []string) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+	cle.SetAlias("b", "block")
+
+	cle.SetCommandTimeout("block", 10*time.Millisecond)
+	err := cle.ExecLine("b")
+	assert.Equal(t, ErrTimeout{Command: "block", Timeout: 10 * time.Millisecond}, err)
+	assert.EqualError(t, err, "block timed out after 10ms")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// default timeout for all commands
+	cle.SetCommandTimeout("block", 0)
+	cle.CommandTimeout = 20 * time.Millisecond
+	assert.Equal(t, ErrTimeout{Command: "block", Timeout: 20 * time.Millisecond}, cle.ExecLine("block"))
+	require.NoError(t, cle.ExecLine("print a"))
+	assert.Equal(t, ">a<|", sb.String())
+}
+
+func TestCtrlCDiscardsLine(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		input.PutString("print a")
+		input.PutKeys(console.KeyCtrlC)
+		input.PutString("print 'b\nc")
+		input.PutKeys(console.KeyCtrlC)
+		input.PutString("print d\nexit\n")
+
+		cle, _, sb := prepareTestCLE()
+		require.NoError(t, cle.Run())
+		assert.Equal(t, ">d<|", sb.String())
+		// discarded lines stay visible
+		assert.Contains(t, output.String(), " a^C")
+		assert.Contains(t, output.String(), "c"+ansiReset+"^C")
+		input.AssertBufferConsumed(t)
+	})
+
+	consoletest.WithMocks(func(input *consoletest.MockInput) {
+		input.PutString("print a")
+		input.PutKeys(console.KeyCtrlC)
+
+		cle, _, _ := prepareTestCLE()
+		cle.DiscardLineOnCtrlC = false
+		assert.Equal(t, ErrCtrlC, cle.Run())
+	})
+}
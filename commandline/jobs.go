@@ -0,0 +1,458 @@
+package commandline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/DENICeG/go-console/v2"
+)
+
+// JobState denotes the state of a background job.
+type JobState int
+
+const (
+	// JobRunning denotes a job that has not finished yet.
+	JobRunning JobState = iota
+	// JobDone denotes a job that finished without error.
+	JobDone
+	// JobFailed denotes a job that returned an error.
+	JobFailed
+	// JobKilled denotes a job that has been killed.
+	JobKilled
+)
+
+func (s JobState) String() string {
+	switch s {
+	case JobRunning:
+		return "Running"
+	case JobDone:
+		return "Done"
+	case JobFailed:
+		return "Failed"
+	case JobKilled:
+		return "Killed"
+	}
+	return fmt.Sprintf("JobState(%d)", int(s))
+}
+
+// Job denotes a command line that is executed in the background, which is requested by a trailing &.
+type Job struct {
+	id      int
+	command string
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mutex      sync.Mutex
+	err        error
+	killed     bool
+	foreground bool
+}
+
+// ID returns the number of the job as used by fg and kill.
+func (j *Job) ID() int {
+	return j.id
+}
+
+// Command returns the command line of the job.
+func (j *Job) Command() string {
+	return j.command
+}
+
+// Done returns a channel that is closed when the job has finished.
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Wait blocks until the job has finished and returns its error.
+func (j *Job) Wait() error {
+	<-j.done
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.err
+}
+
+// Kill cancels the context of the job. Commands that do not implement ContextCommand run until they are finished.
+func (j *Job) Kill() {
+	j.mutex.Lock()
+	j.killed = true
+	j.mutex.Unlock()
+	j.cancel()
+}
+
+// isForeground returns true if the job has been moved to the foreground by fg.
+func (j *Job) isForeground() bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.foreground
+}
+
+// State returns the current state of the job.
+func (j *Job) State() JobState {
+	select {
+	case <-j.done:
+	default:
+		return JobRunning
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.finalState()
+}
+
+// finalState returns the state of the finished job. The mutex must be held.
+func (j *Job) finalState() JobState {
+	switch {
+	case j.killed:
+		return JobKilled
+	case j.err != nil:
+		return JobFailed
+	}
+	return JobDone
+}
+
+// Jobs returns all running background jobs sorted by their number.
+func (b *Environment) Jobs() []*Job {
+	b.jobMutex.Lock()
+	defer b.jobMutex.Unlock()
+
+	jobs := make([]*Job, 0, len(b.jobs))
+	for _, j := range b.jobs {
+		jobs = append(jobs, j)
+	}
+	sort.Slice(jobs, func(i, k int) bool {
+		return jobs[i].id < jobs[k].id
+	})
+	return jobs
+}
+
+// Job returns the running background job with the given number.
+func (b *Environment) Job(id int) (*Job, bool) {
+	b.jobMutex.Lock()
+	defer b.jobMutex.Unlock()
+	j, exists := b.jobs[id]
+	return j, exists
+}
+
+// startJob executes p in the background. Its output is printed above the edited line and its completion is announced.
+func (b *Environment) startJob(p *Pipeline) (*Job, error) {
+	if _, err := b.requireStreams(p); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := &outputWriter{out: console.DefaultOutput}
+	j := b.addJob(p.String(), cancel, out)
+	go func() {
+		defer cancel()
+		w := &jobWriter{out: out}
+		_, err := b.execPipeline(ctx, p, Streams{Stdin: strings.NewReader(""), Stdout: w, Stderr: w})
+		w.Flush() //nolint
+		b.finishJob(j, err, out)
+	}()
+
+	return j, nil
+}
+
+// addJob registers a running job for the command line and announces it to out.
+func (b *Environment) addJob(command string, cancel context.CancelFunc, out *outputWriter) *Job {
+	j := &Job{command: command, cancel: cancel, done: make(chan struct{})}
+
+	b.jobMutex.Lock()
+	// job numbers start at 1 again when all jobs have finished
+	for _, other := range b.jobs {
+		j.id = max(j.id, other.id)
+	}
+	j.id++
+	b.jobs[j.id] = j
+	b.jobMutex.Unlock()
+
+	fmt.Fprintf(out, "[%d] %s\n", j.id, j.command) //nolint
+	return j
+}
+
+// finishJob sets the result of j, announces it to out unless the job is in the foreground and removes the job.
+func (b *Environment) finishJob(j *Job, err error, out *outputWriter) {
+	if errors.Is(err, ErrExit) {
+		// background jobs do not stop the environment
+		err = nil
+	}
+
+	j.mutex.Lock()
+	j.err = err
+	if !j.foreground {
+		// announce before anyone waiting for the job continues
+		out.Write([]byte(j.status())) //nolint
+	}
+	j.mutex.Unlock()
+
+	b.jobMutex.Lock()
+	delete(b.jobs, j.id)
+	b.jobMutex.Unlock()
+	close(j.done)
+}
+
+// jobWriter writes the output of a background job line by line, because text that is printed above the prompt always ends with a line break.
+// Incomplete lines are kept until they are completed or Flush is called.
+type jobWriter struct {
+	mutex  sync.Mutex
+	out    io.Writer
+	buffer []byte
+}
+
+func (w *jobWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.buffer = append(w.buffer, p...)
+	end := bytes.LastIndexByte(w.buffer, '\n') + 1
+	if end == 0 {
+		return len(p), nil
+	}
+	lines := w.buffer[:end]
+	w.buffer = append([]byte(nil), w.buffer[end:]...)
+	if _, err := w.out.Write(lines); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes an incomplete last line.
+func (w *jobWriter) Flush() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if len(w.buffer) == 0 {
+		return nil
+	}
+	_, err := w.out.Write(w.buffer)
+	w.buffer = nil
+	return err
+}
+
+// status returns a line that announces the state of the finished job. The mutex must be held.
+func (j *Job) status() string {
+	state := j.finalState()
+	line := fmt.Sprintf("[%d] %-7s %s", j.id, state.String(), j.command)
+	if state == JobFailed {
+		line += ": " + j.err.Error()
+	}
+	return line + "\n"
+}
+
+// String returns the command line of the pipeline.
+func (p *Pipeline) String() string {
+	commands := make([]string, len(p.Commands))
+	for i, c := range p.Commands {
+		commands[i] = GetCommandString(c.Args)
+		if len(c.Input) > 0 {
+			commands[i] += " < " + Quote(c.Input)
+		}
+		if len(c.Output) > 0 {
+			if c.Append {
+				commands[i] += " >> " + Quote(c.Output)
+			} else {
+				commands[i] += " > " + Quote(c.Output)
+			}
+		}
+	}
+	return strings.Join(commands, " | ")
+}
+
+// findJob returns the job denoted by arg like 1 or %1, or the most recent job if arg is empty.
+func (b *Environment) findJob(arg string) (*Job, error) {
+	if len(arg) == 0 {
+		jobs := b.Jobs()
+		if len(jobs) == 0 {
+			return nil, fmt.Errorf("no current job")
+		}
+		return jobs[len(jobs)-1], nil
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(arg, "%"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid job number %q", arg)
+	}
+	j, exists := b.Job(id)
+	if !exists {
+		return nil, fmt.Errorf("no such job %d", id)
+	}
+	return j, nil
+}
+
+// jobCompletionOptions returns the numbers of all running jobs described by their command lines.
+func (b *Environment) jobCompletionOptions(_ []string, _ int) []CompletionOption {
+	jobs := b.Jobs()
+	options := make([]CompletionOption, len(jobs))
+	for i, j := range jobs {
+		options[i] = NewDescribedCompletionOption(strconv.Itoa(j.id), j.command, false)
+	}
+	return options
+}
+
+// NewJobsCommand returns a named command that lists all running background jobs.
+func NewJobsCommand(name string, env *Environment) Command {
+	return Describe(NewParameterlessCommand(name, func([]string) error {
+		for _, j := range env.Jobs() {
+			if _, err := console.Printlnf("[%d] %-7s %s", j.id, JobRunning.String(), j.command); err != nil {
+				return err
+			}
+		}
+		return nil
+	}), CommandInfo{Description: "list background jobs", Category: "Jobs"})
+}
+
+// NewFgCommand returns a named command that waits for a background job in the foreground and returns its error. Without argument, the most recent job is used.
+//
+// The job is killed if the command is cancelled.
+func NewFgCommand(name string, env *Environment) Command {
+	return Describe(NewContextCommand(name, env.jobCompletionOptions, func(ctx context.Context, args []string) error {
+		if len(args) > 1 {
+			return ErrInvalidUsage{Err: fmt.Errorf("too many arguments"), Usage: fmt.Sprintf("Usage: %s [job]\n", name)}
+		}
+		arg := ""
+		if len(args) > 0 {
+			arg = args[0]
+		}
+		j, err := env.findJob(arg)
+		if err != nil {
+			return err
+		}
+
+		j.mutex.Lock()
+		j.foreground = true
+		j.mutex.Unlock()
+		console.Println(j.command) //nolint
+
+		select {
+		case <-j.done:
+		case <-ctx.Done():
+			j.Kill()
+		}
+		return j.Wait()
+	}), CommandInfo{
+		Description: "wait for a background job in the foreground",
+		Usage:       fmt.Sprintf("Usage: %s [job]\n", name),
+		Category:    "Jobs",
+	})
+}
+
+// NewWaitCommand returns a named command that waits for the given background jobs or for all jobs without arguments.
+func NewWaitCommand(name string, env *Environment) Command {
+	return Describe(NewContextCommand(name, env.jobCompletionOptions, func(ctx context.Context, args []string) error {
+		jobs := env.Jobs()
+		if len(args) > 0 {
+			jobs = make([]*Job, len(args))
+			for i, arg := range args {
+				j, err := env.findJob(arg)
+				if err != nil {
+					return err
+				}
+				jobs[i] = j
+			}
+		}
+
+		for _, j := range jobs {
+			select {
+			case <-j.done:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	}), CommandInfo{
+		Description: "wait for background jobs to finish",
+		Usage:       fmt.Sprintf("Usage: %s [job...]\n", name),
+		Category:    "Jobs",
+	})
+}
+
+// NewKillCommand returns a named command that kills background jobs by cancelling their context.
+func NewKillCommand(name string, env *Environment) Command {
+	return Describe(NewCustomCommand(name, env.jobCompletionOptions, func(args []string) error {
+		if len(args) == 0 {
+			return ErrInvalidUsage{Err: fmt.Errorf("missing job"), Usage: fmt.Sprintf("Usage: %s <job>...\n", name)}
+		}
+		for _, arg := range args {
+			j, err := env.findJob(arg)
+			if err != nil {
+				return err
+			}
+			j.Kill()
+		}
+		return nil
+	}), CommandInfo{
+		Description: "kill background jobs",
+		Usage:       fmt.Sprintf("Usage: %s <job>...\n", name),
+		Category:    "Jobs",
+	})
+}
+
+// activeRenderer denotes the renderer of the line that is currently edited.
+var activeRenderer struct {
+	sync.Mutex
+	renderer *lineRenderer
+}
+
+func setActiveRenderer(r *lineRenderer) {
+	activeRenderer.Lock()
+	defer activeRenderer.Unlock()
+	activeRenderer.renderer = r
+}
+
+// printAbovePrompt prints text above the edited line and returns false if no line is edited.
+func printAbovePrompt(text string) bool {
+	activeRenderer.Lock()
+	defer activeRenderer.Unlock()
+	if activeRenderer.renderer == nil {
+		return false
+	}
+	activeRenderer.renderer.PrintAbove(text)
+	return true
+}
+
+// promptOutput is a console output that prints above the edited line while a command is read.
+type promptOutput struct {
+	console.Output
+}
+
+func (o *promptOutput) Print(str string) (int, error) {
+	if printAbovePrompt(str) {
+		return len(str), nil
+	}
+	return o.Output.Print(str)
+}
+
+func (o *promptOutput) Unwrap() console.Output {
+	return o.Output
+}
+
+// installPromptOutput replaces the console output by a promptOutput and returns a function to restore the previous output.
+func installPromptOutput() func() {
+	if _, ok := console.DefaultOutput.(*promptOutput); ok {
+		return func() {}
+	}
+
+	previous := console.DefaultOutput
+	output := &promptOutput{Output: previous}
+	console.DefaultOutput = output
+	return func() {
+		if console.DefaultOutput == output {
+			console.DefaultOutput = previous
+		}
+	}
+}
+
+// rawOutput returns the console output without promptOutput, which is used by the line editor itself.
+func rawOutput() console.Output {
+	if o, ok := console.DefaultOutput.(*promptOutput); ok {
+		return o.Output
+	}
+	return console.DefaultOutput
+}
@@ -0,0 +1,229 @@
+package commandline
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DENICeG/go-console/v2"
+	"github.com/DENICeG/go-console/v2/consoletest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBackgroundCommandList(t *testing.T) {
+	l, err := ParseCommandList("sync a & list | grep x & login && logout &")
+	require.NoError(t, err)
+	assert.Equal(t, []CommandListItem{
+		{Pipeline: &Pipeline{Commands: []PipelineCommand{{Args: []string{"sync", "a"}}}}, Background: true},
+		{Operator: OperatorSequence, Pipeline: &Pipeline{Commands: []PipelineCommand{
+			{Args: []string{"list"}},
+			{Args: []string{"grep", "x"}},
+		}}, Background: true},
+		{Operator: OperatorSequence, Pipeline: &Pipeline{Commands: []PipelineCommand{{Args: []string{"login"}}}}},
+		{Operator: OperatorAnd, Pipeline: &Pipeline{Commands: []PipelineCommand{{Args: []string{"logout"}}}}, Background: true},
+	}, l.Items)
+
+	for line, expected := range map[string]string{
+		"& a":     `syntax error near "&"`,
+		"a & & b": `syntax error near "&"`,
+		"a & ; b": `syntax error near ";"`,
+	} {
+		_, err := ParseCommandList(line)
+		assert.EqualError(t, err, expected, line)
+	}
+}
+
+func TestPipelineString(t *testing.T) {
+	l, err := ParseCommandList(`list "a b" < in.txt | grep x >> out.txt`)
+	require.NoError(t, err)
+	assert.Equal(t, `list "a b" < in.txt | grep x >> out.txt`, l.Items[0].Pipeline.String())
+}
+
+// prepareJobCLE returns an environment with a command that blocks until it is released or cancelled. The command fails if its first argument is fail.
+func prepareJobCLE() (*Environment, chan struct{}) {
+	cle, _, _ := prepareTestCLE()
+	release := make(chan struct{})
+	cle.RegisterCommand(NewContextCommand("block", nil, func(ctx context.Context, args []string) error {
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if len(args) > 0 && args[0] == "fail" {
+			return fmt.Errorf("failed")
+		}
+		return nil
+	}))
+	cle.RegisterCommand(NewJobsCommand("jobs", cle))
+	cle.RegisterCommand(NewFgCommand("fg", cle))
+	cle.RegisterCommand(NewWaitCommand("wait", cle))
+	cle.RegisterCommand(NewKillCommand("kill", cle))
+	return cle, release
+}
+
+func TestBackgroundJobs(t *testing.T) {
+	consoletest.WithOutputMocks(func(_ *consoletest.MockInput, output *consoletest.MockOutput) {
+		cle, release := prepareJobCLE()
+
+		require.NoError(t, cle.ExecLine("block a & block fail &"))
+		assert.Equal(t, "[1] block a\n[2] block fail\n", output.String())
+		require.Len(t, cle.Jobs(), 2)
+
+		output.Reset()
+		require.NoError(t, cle.ExecLine("jobs"))
+		assert.Equal(t, "[1] Running block a\n[2] Running block fail\n", output.String())
+
+		output.Reset()
+		close(release)
+		require.NoError(t, cle.ExecLine("wait"))
+		assert.Empty(t, cle.Jobs())
+		announced := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+		assert.ElementsMatch(t, []string{"[1] Done    block a", "[2] Failed  block fail: failed"}, announced)
+	})
+}
+
+func TestKillJob(t *testing.T) {
+	consoletest.WithOutputMocks(func(_ *consoletest.MockInput, output *consoletest.MockOutput) {
+		cle, _ := prepareJobCLE()
+
+		require.NoError(t, cle.ExecLine("block &"))
+		j, exists := cle.Job(1)
+		require.True(t, exists)
+		assert.Equal(t, JobRunning, j.State())
+
+		assert.EqualError(t, cle.ExecLine("kill 2"), "no such job 2")
+		require.NoError(t, cle.ExecLine("kill %1"))
+		assert.ErrorIs(t, j.Wait(), context.Canceled)
+		assert.Equal(t, JobKilled, j.State())
+		assert.Equal(t, "[1] block\n[1] Killed  block\n", output.String())
+
+		// job numbers are reused when all jobs have finished
+		require.NoError(t, cle.ExecLine("block &"))
+		_, exists = cle.Job(1)
+		assert.True(t, exists)
+		require.NoError(t, cle.ExecLine("kill 1"))
+		require.NoError(t, cle.ExecLine("wait"))
+	})
+}
+
+func TestForegroundJob(t *testing.T) {
+	consoletest.WithOutputMocks(func(_ *consoletest.MockInput, output *consoletest.MockOutput) {
+		cle, release := prepareJobCLE()
+
+		assert.EqualError(t, cle.ExecLine("fg"), "no current job")
+
+		require.NoError(t, cle.ExecLine("block fail &"))
+		j, _ := cle.Job(1)
+		go func() {
+			// release the job after fg took it
+			for !j.isForeground() {
+				time.Sleep(time.Millisecond)
+			}
+			close(release)
+		}()
+		assert.EqualError(t, cle.ExecLine("fg"), "failed")
+		// the result of a foreground job is not announced
+		assert.Equal(t, "[1] block fail\nblock fail\n", output.String())
+	})
+}
+
+func TestBackgroundStreamCommand(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		input.PutString("a b\n")
+		cle, _ := prepareJobCLE()
+		cle.RegisterCommand(NewWcCommand("wc"))
+
+		// background jobs do not read console input
+		require.NoError(t, cle.ExecLine("wc &"))
+		require.NoError(t, cle.ExecLine("wait"))
+		assert.Equal(t, "[1] wc\n0 0 0\n[1] Done    wc\n", output.String())
+		assert.False(t, input.BufferConsumed())
+	})
+}
+
+func TestBackgroundJobWithEnvironmentChanges(t *testing.T) {
+	consoletest.WithOutputMocks(func(_ *consoletest.MockInput, output *consoletest.MockOutput) {
+		cle, release := prepareJobCLE()
+		cle.RegisterCommand(NewSetCommand("set", cle))
+		cle.RegisterCommand(NewAliasCommand("alias", cle))
+		cle.SetAlias("b", "block")
+
+		// the job resolves aliases and commands while they are changed in the foreground
+		require.NoError(t, cle.ExecLine("b &"))
+		require.NoError(t, cle.ExecLine("alias b=print"))
+		require.NoError(t, cle.ExecLine("set X y"))
+		cle.SetCommandTimeout("block", time.Minute)
+		cle.EnableGlob("block")
+		cle.Use(func(next Handler) Handler { return next })
+		close(release)
+		require.NoError(t, cle.ExecLine("wait"))
+		assert.Equal(t, "[1] b\n[1] Done    b\n", output.String())
+	})
+}
+
+func TestBackgroundRedirection(t *testing.T) {
+	consoletest.WithOutputMocks(func(_ *consoletest.MockInput, _ *consoletest.MockOutput) {
+		cle, _ := prepareJobCLE()
+		assert.EqualError(t, cle.ExecLine("print a > out.txt &"), "print does not support streams and cannot be used in pipelines or redirected")
+		assert.Empty(t, cle.Jobs())
+	})
+}
+
+func TestPrintAbovePrompt(t *testing.T) {
+	consoletest.WithOutputMocks(func(_ *consoletest.MockInput, output *consoletest.MockOutput) {
+		restore := installPromptOutput()
+		defer restore()
+
+		r := newLineRenderer("cle> ")
+		setActiveRenderer(r)
+		r.Render(renderFrame{line: "foo"})
+
+		output.Reset()
+		_, err := console.Print("job output\nsecond line")
+		require.NoError(t, err)
+		// the prompt is cleared, the text printed and the line redrawn
+		assert.Equal(t, cursorBack(8)+ansiClearToEnd+"job output\r\nsecond line\r\n"+ansiClearToEnd+"cle> foo", output.String())
+
+		// text is printed unchanged while no line is edited
+		r.NewLine()
+		setActiveRenderer(nil)
+		output.Reset()
+		_, err = console.Print("done\n")
+		require.NoError(t, err)
+		assert.Equal(t, "done\n", output.String())
+	})
+}
+
+func TestBackgroundJobPartialLines(t *testing.T) {
+	consoletest.WithOutputMocks(func(_ *consoletest.MockInput, output *consoletest.MockOutput) {
+		restore := installPromptOutput()
+		defer restore()
+		r := newLineRenderer("cle> ")
+		setActiveRenderer(r)
+		defer setActiveRenderer(nil)
+		r.Render(renderFrame{line: "foo"})
+
+		cle, _ := prepareJobCLE()
+		cle.RegisterCommand(NewStreamCommand("partial", nil, func(_ []string, streams Streams) error {
+			for _, str := range []string{"a", "b\nc", "d"} {
+				if _, err := io.WriteString(streams.Stdout, str); err != nil {
+					return err
+				}
+			}
+			return nil
+		}))
+
+		require.NoError(t, cle.ExecLine("partial &"))
+		j, _ := cle.Job(1)
+		require.NoError(t, j.Wait())
+		// partial writes are printed as one line, the remainder when the job finishes
+		assert.Contains(t, output.String(), ansiClearToEnd+"ab\r\n"+ansiClearToEnd+"cle> foo")
+		assert.Contains(t, output.String(), ansiClearToEnd+"cd\r\n"+ansiClearToEnd+"cle> foo")
+		assert.NotContains(t, output.String(), "a\r\n")
+	})
+}
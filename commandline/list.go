@@ -0,0 +1,126 @@
+package commandline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const (
+	// OperatorSequence executes the next pipeline regardless of the result of the previous one.
+	OperatorSequence = ";"
+	// OperatorAnd executes the next pipeline only if the previous one succeeded.
+	OperatorAnd = "&&"
+	// OperatorOr executes the next pipeline only if the previous one failed.
+	OperatorOr = "||"
+	// OperatorBackground executes the previous pipeline as background job and continues with the next one immediately.
+	OperatorBackground = "&"
+)
+
+// commandLineOperators contains all operators of a command line. They are ordered so that longer operators are matched first.
+var commandLineOperators = []string{OperatorAppendOutput, OperatorAnd, OperatorOr, OperatorRedirectOutput, OperatorRedirectInput, OperatorPipe, OperatorSequence, OperatorBackground}
+
+// CommandList denotes pipelines that are joined by ;, &&, || and & like login && sync-zone && logout.
+type CommandList struct {
+	Items []CommandListItem
+}
+
+// CommandListItem denotes a pipeline of a command list.
+type CommandListItem struct {
+	// Operator joins the pipeline to the previous one. It is empty for the first item.
+	Operator string
+	Pipeline *Pipeline
+	// Background is true if the pipeline is followed by & and executed as background job.
+	Background bool
+}
+
+// ParseCommandList parses a command line with pipelines joined by ;, &&, || and &. Quoted or escaped operators are part of the arguments.
+//
+// An empty command line results in a list without items. A trailing ; or & is allowed. A pipeline followed by & is joined to the next one by ;.
+func ParseCommandList(line string) (*CommandList, error) {
+	return parseCommandList(line, nil)
+}
+
+func parseCommandList(line string, expand func(string) string) (*CommandList, error) {
+	items, isComplete := lexCommandLine(line, commandLineOperators, expand)
+	if !isComplete {
+		return nil, fmt.Errorf("unterminated quote or escape sequence")
+	}
+
+	l := &CommandList{Items: make([]CommandListItem, 0)}
+	operator := ""
+	start := 0
+	for i := 0; i <= len(items); i++ {
+		if i < len(items) && !isListOperator(items[i].operator) {
+			continue
+		}
+
+		if start == i {
+			if i == len(items) && (operator == OperatorSequence || operator == OperatorBackground || len(l.Items) == 0) {
+				// trailing ;, & or empty command line
+				break
+			}
+			if i == len(items) {
+				return nil, ErrSyntax{}
+			}
+			return nil, ErrSyntax{Operator: items[i].operator}
+		}
+
+		p, err := newPipeline(items[start:i])
+		if err != nil {
+			return nil, err
+		}
+		if operator == OperatorBackground {
+			// the background job does not have a result
+			operator = OperatorSequence
+		}
+		l.Items = append(l.Items, CommandListItem{Operator: operator, Pipeline: p})
+		if i < len(items) {
+			operator = items[i].operator
+			l.Items[len(l.Items)-1].Background = operator == OperatorBackground
+		}
+		start = i + 1
+	}
+	return l, nil
+}
+
+// isListOperator returns true if operator joins pipelines.
+func isListOperator(operator string) bool {
+	return operator == OperatorSequence || operator == OperatorAnd || operator == OperatorOr || operator == OperatorBackground
+}
+
+// execCommandList executes the pipelines of l depending on the result of the previous one. ErrExit stops the execution immediately.
+//
+// Background pipelines are started as jobs and only fail if they cannot be started. Cancellation of ctx stops the execution before the next pipeline.
+// The error of the last executed pipeline is returned. Errors of pipelines that are followed by another executed pipeline are passed to ErrorHandler.
+// The returned command denotes the one that caused the error.
+func (b *Environment) execCommandList(ctx context.Context, l *CommandList, streams Streams) ([]string, error) {
+	var lastCmd []string
+	var lastErr error
+	for i, item := range l.Items {
+		if err := ctx.Err(); i > 0 && err != nil {
+			// interrupted commands stop the whole list
+			return lastCmd, err
+		}
+		if i > 0 && ((item.Operator == OperatorAnd && lastErr != nil) || (item.Operator == OperatorOr && lastErr == nil)) {
+			continue
+		}
+
+		if lastErr != nil && b.ErrorHandler != nil {
+			// the error is not the result of the command list
+			b.ErrorHandler(commandName(lastCmd), commandArgs(lastCmd), lastErr)
+		}
+
+		if item.Background {
+			lastCmd = item.Pipeline.Commands[0].Args
+			_, lastErr = b.startJob(item.Pipeline)
+			continue
+		}
+
+		lastCmd, lastErr = b.execPipeline(ctx, item.Pipeline, streams)
+		if errors.Is(lastErr, ErrExit) {
+			return lastCmd, lastErr
+		}
+	}
+	return lastCmd, lastErr
+}
@@ -0,0 +1,148 @@
+package commandline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/DENICeG/go-console/v2/consoletest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCommandList(t *testing.T) {
+	l, err := ParseCommandList(`login && sync "a && b" 'x;y' a\;b||echo failed;list | grep x > out.txt;`)
+	require.NoError(t, err)
+	assert.Equal(t, []CommandListItem{
+		{Pipeline: &Pipeline{Commands: []PipelineCommand{{Args: []string{"login"}}}}},
+		{Operator: OperatorAnd, Pipeline: &Pipeline{Commands: []PipelineCommand{{Args: []string{"sync", "a && b", "x;y", "a;b"}}}}},
+		{Operator: OperatorOr, Pipeline: &Pipeline{Commands: []PipelineCommand{{Args: []string{"echo", "failed"}}}}},
+		{Operator: OperatorSequence, Pipeline: &Pipeline{Commands: []PipelineCommand{
+			{Args: []string{"list"}},
+			{Args: []string{"grep", "x"}, Output: "out.txt"},
+		}}},
+	}, l.Items)
+
+	l, err = ParseCommandList("")
+	require.NoError(t, err)
+	assert.Empty(t, l.Items)
+
+	for line, expected := range map[string]string{
+		"&& a":       `syntax error near "&&"`,
+		"a ;; b":     `syntax error near ";"`,
+		"a || && b":  `syntax error near "&&"`,
+		"a &&":       "syntax error: unexpected end of command line",
+		"a | ; b":    "syntax error: unexpected end of command line",
+		";":          `syntax error near ";"`,
+		"a && 'b":    "unterminated quote or escape sequence",
+		"a && > out": `syntax error near ">"`,
+	} {
+		_, err := ParseCommandList(line)
+		assert.EqualError(t, err, expected, line)
+	}
+}
+
+func prepareListCLE() (*Environment, *strings.Builder, *[]string) {
+	cle, _, sb := prepareTestCLE()
+	cle.RegisterCommand(NewCustomCommand("fail", nil, func(args []string) error {
+		sb.WriteString("fail|")
+		return fmt.Errorf("failed with %s", strings.Join(args, ","))
+	}))
+
+	handled := make([]string, 0)
+	cle.ErrorHandler = func(cmd string, _ []string, err error) error {
+		handled = append(handled, cmd+": "+err.Error())
+		return nil
+	}
+	return cle, sb, &handled
+}
+
+func TestExecLineCommandList(t *testing.T) {
+	cle, sb, handled := prepareListCLE()
+
+	require.NoError(t, cle.ExecLine("print a && print b; print c"))
+	assert.Equal(t, ">a<|>b<|>c<|", sb.String())
+
+	sb.Reset()
+	cmd, err := cle.execLine(context.Background(), "print a && fail x && print b")
+	assert.EqualError(t, err, "failed with x")
+	assert.Equal(t, []string{"fail", "x"}, cmd)
+	assert.Equal(t, ">a<|fail|", sb.String())
+	assert.Empty(t, *handled)
+
+	sb.Reset()
+	require.NoError(t, cle.ExecLine("fail x || print b && print c || print d"))
+	assert.Equal(t, "fail|>b<|>c<|", sb.String())
+	// errors that do not end the command list are passed to the error handler
+	assert.Equal(t, []string{"fail: failed with x"}, *handled)
+
+	sb.Reset()
+	*handled = (*handled)[:0]
+	err = cle.ExecLine("fail x; fail y")
+	assert.EqualError(t, err, "failed with y")
+	assert.Equal(t, []string{"fail: failed with x"}, *handled)
+
+	sb.Reset()
+	err = cle.ExecLine("print a || exit; print b")
+	assert.NoError(t, err)
+	assert.Equal(t, ">a<|>b<|", sb.String())
+
+	sb.Reset()
+	err = cle.ExecLine("print a && exit; print b")
+	assert.ErrorIs(t, err, ErrExit)
+	assert.Equal(t, ">a<|", sb.String())
+}
+
+func TestRunCommandList(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, _ *consoletest.MockOutput) {
+		input.NonInteractive = true
+		input.PutString("print login && fail sync && print logout\nprint '&&' \\; && exit ; print unreachable\nprint unreachable\n")
+
+		cle, sb, handled := prepareListCLE()
+		require.NoError(t, cle.Run())
+		assert.Equal(t, ">login<|fail|>&&<>;<|", sb.String())
+		assert.Equal(t, []string{"fail: failed with sync"}, *handled)
+	})
+}
+
+func TestQuotedOperatorsValidation(t *testing.T) {
+	cle, _, _ := prepareListCLE()
+	for _, line := range []string{`print "|"`, `print "&&"`, `print 'a;'`, `print "&"`, `print \; '||'`} {
+		assert.NoError(t, cle.ValidateCommandLine(line), line)
+	}
+
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		input.PutString("print \"|\" \"&&\" 'a;' \\&\nexit\n")
+
+		cle, sb, handled := prepareListCLE()
+		require.NoError(t, cle.Run())
+		assert.Equal(t, ">|<>&&<>a;<>&<|", sb.String())
+		assert.Empty(t, *handled)
+		assert.NotContains(t, output.String(), "syntax error")
+		input.AssertBufferConsumed(t)
+	})
+}
+
+func TestCommandListValidationAndHighlighting(t *testing.T) {
+	cle, _, _ := prepareListCLE()
+
+	assert.NoError(t, cle.ValidateCommandLine("print a && fail ;"))
+	assert.Equal(t, NewErrInvalidArgument(0, `syntax error near "&&"`), cle.ValidateCommandLine("&& print"))
+	assert.Equal(t, NewErrInvalidArgument(2, `syntax error near "||"`), cle.ValidateCommandLine("print ; || print"))
+	assert.Equal(t, NewErrInvalidArgument(1, "syntax error: unexpected end of command line"), cle.ValidateCommandLine("print &&"))
+
+	options, _ := cle.CompleteCommandLine("print a || fa", []string{"print", "a", "||", "fa"})
+	assert.Contains(t, optionReplacements(options), "fail")
+
+	line := "print && x || fail"
+	tokens, _ := TokenizeCommand(line)
+	assert.Equal(t, []Span{
+		{Start: 0, End: 5, Style: styleKnownCommand},
+		{Start: 6, End: 8, Style: styleOperator},
+		{Start: 9, End: 10, Style: styleUnknownCommand},
+		{Start: 11, End: 13, Style: styleOperator},
+		{Start: 14, End: 18, Style: styleKnownCommand},
+	}, cle.HighlightCommand(line, tokens))
+}
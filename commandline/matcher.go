@@ -0,0 +1,134 @@
+package commandline
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Matcher decides whether a completion option matches the entered text of a command part.
+//
+// Higher scores are ranked first. Matches with equal scores are sorted alphabetically.
+type Matcher func(candidate, input string) (score int, ok bool)
+
+var (
+	// PrefixMatcher matches all candidates that start with the input. This is the default matcher.
+	PrefixMatcher Matcher = func(candidate, input string) (int, bool) {
+		return 0, strings.HasPrefix(candidate, input)
+	}
+
+	// CaseInsensitivePrefixMatcher matches all candidates that start with the input ignoring the case. Candidates with the exact case are ranked first.
+	CaseInsensitivePrefixMatcher Matcher = func(candidate, input string) (int, bool) {
+		if strings.HasPrefix(candidate, input) {
+			return 1, true
+		}
+		return 0, hasPrefixFold(candidate, input)
+	}
+
+	// SubstringMatcher matches all candidates that contain the input ignoring the case. Earlier occurrences are ranked first.
+	SubstringMatcher Matcher = func(candidate, input string) (int, bool) {
+		index := strings.Index(strings.ToLower(candidate), strings.ToLower(input))
+		if index < 0 {
+			return 0, false
+		}
+		return -index, true
+	}
+
+	// FuzzyMatcher matches all candidates that contain the runes of the input in the same order ignoring the case.
+	//
+	// Consecutive runes and matches at the beginning of words are ranked first.
+	FuzzyMatcher Matcher = fuzzyMatch
+)
+
+const (
+	fuzzyScoreMatch       = 1
+	fuzzyScoreConsecutive = 4
+	fuzzyScoreWordStart   = 3
+	fuzzyMaxGapPenalty    = 3
+)
+
+func fuzzyMatch(candidate, input string) (int, bool) {
+	runes := []rune(strings.ToLower(candidate))
+	score := 0
+	pos := 0
+	lastMatch := -2
+
+	for _, r := range strings.ToLower(input) {
+		index := -1
+		for i := pos; i < len(runes); i++ {
+			if runes[i] == r {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return 0, false
+		}
+
+		score += fuzzyScoreMatch
+		if index == lastMatch+1 {
+			score += fuzzyScoreConsecutive
+		}
+		if index == 0 || isWordSeparator(runes[index-1]) {
+			score += fuzzyScoreWordStart
+		}
+		// skipped runes between matches are penalized
+		score -= min(index-pos, fuzzyMaxGapPenalty)
+
+		lastMatch = index
+		pos = index + 1
+	}
+
+	return score, true
+}
+
+func isWordSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+func hasPrefixFold(str, prefix string) bool {
+	strRunes := []rune(str)
+	prefixRunes := []rune(prefix)
+	if len(prefixRunes) > len(strRunes) {
+		return false
+	}
+	return strings.EqualFold(string(strRunes[:len(prefixRunes)]), prefix)
+}
+
+// matchOptions returns all options that match the prefix ranked by score.
+func matchOptions(options []CompletionOption, prefix string, matcher Matcher) []CompletionOption {
+	if options == nil {
+		return nil
+	}
+	if matcher == nil {
+		matcher = PrefixMatcher
+	}
+
+	type match struct {
+		option CompletionOption
+		score  int
+	}
+
+	matches := make([]match, 0)
+	for _, c := range options {
+		if _, ok := c.(*globCompletionOption); ok {
+			// glob matches do not start with the pattern
+			matches = append(matches, match{c, 0})
+		} else if score, ok := matcher(c.Replacement(), prefix); ok {
+			matches = append(matches, match{c, score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].option.String() < matches[j].option.String()
+	})
+
+	filtered := make([]CompletionOption, len(matches))
+	for i := range matches {
+		filtered[i] = matches[i].option
+	}
+	return filtered
+}
@@ -0,0 +1,92 @@
+package commandline
+
+import (
+	"testing"
+
+	"github.com/DENICeG/go-console/v2/consoletest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchers(t *testing.T) {
+	_, ok := PrefixMatcher("DENIC-123", "DENIC")
+	assert.True(t, ok)
+	_, ok = PrefixMatcher("DENIC-123", "denic")
+	assert.False(t, ok)
+
+	_, ok = CaseInsensitivePrefixMatcher("DENIC-123", "denic")
+	assert.True(t, ok)
+	_, ok = CaseInsensitivePrefixMatcher("DENIC-123", "123")
+	assert.False(t, ok)
+
+	score, ok := SubstringMatcher("DENIC-123", "c-1")
+	assert.True(t, ok)
+	assert.Equal(t, -4, score)
+	_, ok = SubstringMatcher("DENIC-123", "c1")
+	assert.False(t, ok)
+
+	_, ok = FuzzyMatcher("DENIC-123", "dc13")
+	assert.True(t, ok)
+	_, ok = FuzzyMatcher("DENIC-123", "dc31")
+	assert.False(t, ok)
+
+	// consecutive runes and word starts score better than scattered matches
+	consecutive, _ := FuzzyMatcher("registrar-handle", "hand")
+	scattered, _ := FuzzyMatcher("registrar-handle", "rgsr")
+	assert.Greater(t, consecutive, scattered)
+}
+
+func TestMatchOptionsRanking(t *testing.T) {
+	options := PrepareCompletionOptions([]string{"zone-info", "info", "domain-info", "inform"}, false)
+
+	assert.Equal(t, []string{"info", "inform"}, replacements(matchOptions(options, "inf", nil)))
+	assert.Equal(t, []string{"info", "inform", "zone-info", "domain-info"}, replacements(matchOptions(options, "inf", SubstringMatcher)))
+	assert.Equal(t, []string{"info", "inform", "zone-info", "domain-info"}, replacements(matchOptions(options, "if", FuzzyMatcher)))
+}
+
+func TestFindLongestCommonPrefix(t *testing.T) {
+	options := PrepareCompletionOptions([]string{"Domain-1", "domain-2", "DOMAIN-3"}, false)
+	assert.Equal(t, "", findLongestCommonPrefix(options, false))
+	assert.Equal(t, "Domain-", findLongestCommonPrefix(options, true))
+	assert.Equal(t, "", findLongestCommonPrefix(nil, true))
+}
+
+func TestCommandLineEnvironmentMatcher(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		input.PutString("print doM\t1\n")
+		input.PutString("print 2\t\n")
+		input.PutString("exit\n")
+
+		cle, _, sb := prepareTestCLE()
+		cle.Matcher = CaseInsensitivePrefixMatcher
+		cle.RegisterCommand(NewCustomCommand("print",
+			NewFixedArgCompletion(NewOneOfArgCompletion("Domain-1", "domain-2", "registrar-2")),
+			newPrintHandler(sb)))
+
+		assert.NoError(t, cle.Run())
+		assert.Equal(t, ">Domain-1<|>2<|", sb.String())
+
+		input.PutString("print in-2\t\n")
+		input.PutString("exit\n")
+		cle.Matcher = SubstringMatcher
+		sb.Reset()
+		assert.NoError(t, cle.Run())
+		assert.Equal(t, ">domain-2<|", sb.String())
+
+		input.PutString("print rg2\t\n")
+		input.PutString("exit\n")
+		cle.Matcher = FuzzyMatcher
+		sb.Reset()
+		assert.NoError(t, cle.Run())
+		assert.Equal(t, ">registrar-2<|", sb.String())
+		input.AssertBufferConsumed(t)
+	})
+}
+
+func replacements(options []CompletionOption) []string {
+	list := make([]string, len(options))
+	for i, o := range options {
+		list[i] = o.Replacement()
+	}
+	return list
+}
@@ -0,0 +1,136 @@
+package commandline
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DENICeG/go-console/v2"
+)
+
+// defaultMenuRows is used when the terminal height is unknown.
+const defaultMenuRows = 10
+
+// completionMenu denotes the state of an interactive completion menu that is displayed below the command line.
+type completionMenu struct {
+	options  []CompletionOption
+	selected int
+	// firstRow denotes the first visible row for scrolling menus.
+	firstRow int
+	// columns denotes the number of columns of the last rendered grid.
+	columns int
+}
+
+func newCompletionMenu(options []CompletionOption) *completionMenu {
+	return &completionMenu{options: options, columns: 1}
+}
+
+// Selected returns the currently highlighted option.
+func (m *completionMenu) Selected() CompletionOption {
+	return m.options[m.selected]
+}
+
+// Move changes the selection by delta entries and wraps around at both ends.
+func (m *completionMenu) Move(delta int) {
+	m.selected = ((m.selected+delta)%len(m.options) + len(m.options)) % len(m.options)
+}
+
+// HandleKey moves the selection according to the given key and returns false if the key is not used by the menu.
+func (m *completionMenu) HandleKey(key console.Key) bool {
+	switch key {
+	case console.KeyTab, console.KeyRight:
+		m.Move(1)
+	case console.KeyLeft:
+		m.Move(-1)
+	case console.KeyDown:
+		m.Move(m.columns)
+	case console.KeyUp:
+		m.Move(-m.columns)
+	default:
+		return false
+	}
+	return true
+}
+
+// Lines returns the rendered menu for a terminal of the given size.
+//
+// The grid is filled row by row like PrintList does. Options with descriptions are listed one per row.
+func (m *completionMenu) Lines(width, height int) []string {
+	labelWidth := 0
+	for _, o := range m.options {
+		labelWidth = max(labelWidth, displayWidth(o.String()))
+	}
+
+	// every cell has a leading marker column and cells are separated by a space
+	cellWidth := labelWidth + 2
+	m.columns = 1
+	withDescriptions := hasDescriptions(m.options)
+	if width > 0 && !withDescriptions {
+		m.columns = max((width+1)/cellWidth, 1)
+	}
+	rows := (len(m.options) + m.columns - 1) / m.columns
+
+	visibleRows := defaultMenuRows
+	if height > 0 {
+		// keep the command line and a status line visible
+		visibleRows = max(height-2, 1)
+	}
+	visibleRows = min(visibleRows, rows)
+
+	// scroll selection into view
+	selectedRow := m.selected / m.columns
+	if selectedRow < m.firstRow {
+		m.firstRow = selectedRow
+	}
+	if selectedRow >= m.firstRow+visibleRows {
+		m.firstRow = selectedRow - visibleRows + 1
+	}
+
+	colors := console.SupportsColors()
+	lines := make([]string, 0, visibleRows+1)
+	for row := m.firstRow; row < m.firstRow+visibleRows; row++ {
+		var sb strings.Builder
+		for col := 0; col < m.columns; col++ {
+			index := row*m.columns + col
+			if index >= len(m.options) {
+				break
+			}
+
+			label := m.options[index].String()
+			padding := strings.Repeat(" ", labelWidth-displayWidth(label))
+			switch {
+			case index != m.selected:
+				sb.WriteString(" " + label + padding)
+			case colors:
+				sb.WriteString(" " + styleMenuSelection + label + padding + ansiReset)
+			default:
+				sb.WriteString(">" + label + padding)
+			}
+			if col < m.columns-1 {
+				sb.WriteString(" ")
+			}
+
+			if description := getDescription(m.options[index]); withDescriptions && len(description) > 0 {
+				sb.WriteString(strings.Repeat(" ", listSpaceLen))
+				if width > 0 {
+					// keep the last column free to prevent wrapping
+					description = truncateToWidth(description, width-labelWidth-listSpaceLen-2)
+				}
+				if colors {
+					description = styleDescription + description + ansiReset
+				}
+				sb.WriteString(description)
+			}
+		}
+		lines = append(lines, sb.String())
+	}
+
+	if visibleRows < rows {
+		info := fmt.Sprintf("rows %d to %d of %d", m.firstRow+1, m.firstRow+visibleRows, rows)
+		if colors {
+			info = styleMenuInfo + info + ansiReset
+		}
+		lines = append(lines, info)
+	}
+
+	return lines
+}
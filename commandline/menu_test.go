@@ -0,0 +1,82 @@
+package commandline
+
+import (
+	"testing"
+
+	"github.com/DENICeG/go-console/v2"
+	"github.com/DENICeG/go-console/v2/consoletest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompletionMenuGrid(t *testing.T) {
+	consoletest.WithOutputMocks(func(_ *consoletest.MockInput, output *consoletest.MockOutput) {
+		output.Colors = false
+		menu := newCompletionMenu(PrepareCompletionOptions([]string{"a", "bb", "c", "dd", "e"}, false))
+
+		assert.Equal(t, []string{
+			">a   bb  c ",
+			" dd  e  ",
+		}, menu.Lines(11, 24))
+
+		menu.HandleKey(console.KeyDown)
+		assert.Equal(t, "dd", menu.Selected().Replacement())
+		menu.HandleKey(console.KeyRight)
+		menu.HandleKey(console.KeyRight)
+		assert.Equal(t, "a", menu.Selected().Replacement())
+		menu.HandleKey(console.KeyLeft)
+		assert.Equal(t, "e", menu.Selected().Replacement())
+		assert.False(t, menu.HandleKey(console.KeyEnter))
+	})
+}
+
+func TestCompletionMenuScrolling(t *testing.T) {
+	consoletest.WithOutputMocks(func(_ *consoletest.MockInput, output *consoletest.MockOutput) {
+		output.Colors = false
+		menu := newCompletionMenu(PrepareCompletionOptions([]string{"a", "b", "c", "d", "e"}, false))
+
+		assert.Equal(t, []string{">a", " b", "rows 1 to 2 of 5"}, menu.Lines(2, 4))
+		menu.Move(3)
+		assert.Equal(t, []string{" c", ">d", "rows 3 to 4 of 5"}, menu.Lines(2, 4))
+		menu.Move(-3)
+		assert.Equal(t, []string{">a", " b", "rows 1 to 2 of 5"}, menu.Lines(2, 4))
+	})
+}
+
+func TestCommandLineEnvironmentMenuSelect(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		input.PutString("print f\t\t\n\n")
+		input.PutString("print f\t\t")
+		input.PutKeys(console.KeyEscape)
+		input.PutString("oo3\n")
+		input.PutString("print f\t")
+		input.PutKeys(console.KeyUp)
+		input.PutString("_\n")
+		input.PutString("exit\n")
+
+		cle, _, sb := prepareTestCLE()
+		cle.MenuSelect = true
+		cle.RegisterCommand(NewCustomCommand("print",
+			NewFixedArgCompletion(NewOneOfArgCompletion("foo1", "foo2", "foo3")),
+			newPrintHandler(sb)))
+
+		assert.NoError(t, cle.Run())
+		assert.Equal(t, ">foo2<|>foo3<|>foo3_<|", sb.String())
+		input.AssertBufferConsumed(t)
+	})
+}
+
+func TestCompletionMenuDescriptions(t *testing.T) {
+	consoletest.WithOutputMocks(func(_ *consoletest.MockInput, output *consoletest.MockOutput) {
+		output.Colors = false
+		menu := newCompletionMenu([]CompletionOption{
+			NewDescribedCompletionOption("info", "show domain information", false),
+			NewCompletionOption("update", false),
+		})
+
+		assert.Equal(t, []string{
+			">info    show domain…",
+			" update",
+		}, menu.Lines(22, 24))
+	})
+}
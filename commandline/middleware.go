@@ -0,0 +1,68 @@
+package commandline
+
+import (
+	"context"
+)
+
+// Handler executes the named command with the given arguments after alias expansion and returns its result.
+type Handler func(ctx context.Context, name string, args []string) error
+
+// Middleware wraps the handler of every executed command to add behavior like logging, timing or authorization.
+//
+// Middleware may change name and args before calling next or return an error without calling it.
+type Middleware func(next Handler) Handler
+
+// Use appends middleware to the chain that is applied to every command, including commands of scripts, pipelines and RunArgs.
+//
+// The first middleware is the outermost one. Panics of commands are recovered before middleware sees the result, if RecoverPanickedCommands is set.
+func (b *Environment) Use(middleware ...Middleware) {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+	b.middleware = append(b.middleware, middleware...)
+}
+
+// chain returns handler wrapped by all middleware.
+func (b *Environment) chain(handler Handler) Handler {
+	b.stateMutex.RLock()
+	middleware := b.middleware
+	b.stateMutex.RUnlock()
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
+
+// onStart calls the OnStart hook if set.
+func (b *Environment) onStart() {
+	if b.OnStart != nil {
+		b.OnStart()
+	}
+}
+
+// beforePrompt calls the BeforePrompt hook if set.
+func (b *Environment) beforePrompt() {
+	if b.BeforePrompt != nil {
+		b.BeforePrompt()
+	}
+}
+
+// beforeExec calls the BeforeExec hook if set.
+func (b *Environment) beforeExec(line string) {
+	if b.BeforeExec != nil {
+		b.BeforeExec(line)
+	}
+}
+
+// afterExec calls the AfterExec hook if set.
+func (b *Environment) afterExec(line string, err error) {
+	if b.AfterExec != nil {
+		b.AfterExec(line, err)
+	}
+}
+
+// onExit calls the OnExit hook if set.
+func (b *Environment) onExit(err error) {
+	if b.OnExit != nil {
+		b.OnExit(err)
+	}
+}
@@ -0,0 +1,120 @@
+package commandline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/DENICeG/go-console/v2/consoletest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLogMiddleware returns middleware that logs every command and its result with the given prefix.
+func newLogMiddleware(prefix string, log *[]string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, name string, args []string) error {
+			*log = append(*log, fmt.Sprintf("%s> %s %s", prefix, name, strings.Join(args, ",")))
+			err := next(ctx, name, args)
+			*log = append(*log, fmt.Sprintf("%s< %v", prefix, err))
+			return err
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	cle, sb, _ := prepareListCLE()
+	cle.SetAlias("p", "print")
+	log := make([]string, 0)
+	cle.Use(newLogMiddleware("a", &log), newLogMiddleware("b", &log))
+
+	assert.EqualError(t, cle.ExecLine("p x y && fail z"), "failed with z")
+	assert.Equal(t, ">x<>y<|fail|", sb.String())
+	assert.Equal(t, []string{
+		"a> print x,y", "b> print x,y", "b< <nil>", "a< <nil>",
+		"a> fail z", "b> fail z", "b< failed with z", "a< failed with z",
+	}, log)
+}
+
+func TestMiddlewareAuthorization(t *testing.T) {
+	cle, sb, _ := prepareListCLE()
+	cle.Use(func(next Handler) Handler {
+		return func(ctx context.Context, name string, args []string) error {
+			if name == "fail" {
+				return fmt.Errorf("%s is not allowed", name)
+			}
+			// arguments can be changed
+			return next(ctx, name, append(args, "checked"))
+		}
+	})
+
+	assert.EqualError(t, cle.ExecLine("fail"), "fail is not allowed")
+	require.NoError(t, cle.ExecLine("print a"))
+	assert.Equal(t, ">a<>checked<|", sb.String())
+}
+
+func TestMiddlewarePanic(t *testing.T) {
+	cle := NewEnvironment()
+	cle.RegisterCommand(NewCustomCommand("panic", nil, func([]string) error {
+		panic("oops")
+	}))
+	var result error
+	cle.Use(func(next Handler) Handler {
+		return func(ctx context.Context, name string, args []string) error {
+			result = next(ctx, name, args)
+			return result
+		}
+	})
+
+	assert.EqualError(t, cle.ExecLine("panic"), "oops")
+	assert.Equal(t, NewErrCommandPanicked("oops"), result)
+}
+
+func TestMiddlewareScriptAndRunArgs(t *testing.T) {
+	consoletest.WithOutputMocks(func(_ *consoletest.MockInput, _ *consoletest.MockOutput) {
+		cle, _, _ := prepareListCLE()
+		log := make([]string, 0)
+		cle.Use(newLogMiddleware("m", &log))
+
+		require.NoError(t, cle.RunScript(strings.NewReader("print a\nprint b || print c\n# comment\n")))
+		assert.Equal(t, ExitCodeError, cle.RunArgs([]string{"fail", "d"}))
+		assert.Equal(t, []string{
+			"m> print a", "m< <nil>",
+			"m> print b", "m< <nil>",
+			"m> fail d", "m< failed with d",
+		}, log)
+	})
+}
+
+func TestLifecycleHooks(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, _ *consoletest.MockOutput) {
+		input.PutString("print a\nfail b\nexit\n")
+
+		cle, _, handled := prepareListCLE()
+		events := make([]string, 0)
+		cle.OnStart = func() { events = append(events, "start") }
+		cle.BeforePrompt = func() { events = append(events, "prompt") }
+		cle.AfterExec = func(line string, err error) { events = append(events, fmt.Sprintf("exec %s: %v", line, err)) }
+		cle.OnExit = func(err error) { events = append(events, fmt.Sprintf("exit: %v", err)) }
+
+		require.NoError(t, cle.Run())
+		assert.Equal(t, []string{
+			"start",
+			"prompt", "exec print a: <nil>",
+			"prompt", "exec fail b: failed with b",
+			"prompt", "exec exit: exit application",
+			"exit: <nil>",
+		}, events)
+		assert.Equal(t, []string{"fail: failed with b"}, *handled)
+
+		events = events[:0]
+		assert.Equal(t, ExitCodeSuccess, cle.RunArgs([]string{"print", "a b"}))
+		require.NoError(t, cle.RunScript(strings.NewReader("print c\nexit\nprint d\n")))
+		assert.Equal(t, []string{
+			"start", `exec print "a b": <nil>`, "exit: <nil>",
+			"start", "exec print c: <nil>", "exec exit: exit application", "exit: <nil>",
+		}, events)
+	})
+}
@@ -0,0 +1,51 @@
+package commandline
+
+import (
+	"io"
+	"testing"
+
+	"github.com/DENICeG/go-console/v2/consoletest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadCommandNonInteractive(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		input.NonInteractive = true
+		input.PutString("foo \"bar\nbaz\"\tbla\nlast")
+
+		cmd, err := ReadCommand("cle", nil)
+		require.NoError(t, err)
+		// tab is part of the command instead of triggering completion
+		assert.Equal(t, []string{"foo", "bar\nbaz\tbla"}, cmd)
+
+		// last line without line break
+		cmd, err = ReadCommand("cle", nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"last"}, cmd)
+
+		_, err = ReadCommand("cle", nil)
+		assert.ErrorIs(t, err, io.EOF)
+
+		input.PutString("foo \"bar")
+		_, err = ReadCommand("cle", nil)
+		assert.EqualError(t, err, "unexpected end of input in unterminated quote")
+
+		// neither prompt nor echo is printed
+		assert.Empty(t, output.String())
+	})
+}
+
+func TestCommandLineEnvironmentNonInteractive(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		input.NonInteractive = true
+		input.PutString("print foo\n\nunknown\nprint bar\n")
+
+		cle, _, sb := prepareTestCLE()
+		assert.NoError(t, cle.Run())
+		input.AssertBufferConsumed(t)
+		assert.Equal(t, ">foo<|>bar<|", sb.String())
+		assert.Equal(t, "Unknown command \"unknown\"\n", output.String())
+	})
+}
@@ -0,0 +1,759 @@
+package commandline
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParameterType denotes the value type of a declared flag or positional argument.
+type ParameterType int
+
+const (
+	// ParameterString accepts any value.
+	ParameterString ParameterType = iota
+	// ParameterInt accepts integer values.
+	ParameterInt
+	// ParameterBool accepts boolean values. Bool flags do not require a value.
+	ParameterBool
+	// ParameterDuration accepts values as understood by time.ParseDuration.
+	ParameterDuration
+	// ParameterEnum accepts one of a fixed set of values.
+	ParameterEnum
+	// ParameterFile accepts a path in the local file system.
+	ParameterFile
+)
+
+func (t ParameterType) String() string {
+	switch t {
+	case ParameterString:
+		return "string"
+	case ParameterInt:
+		return "int"
+	case ParameterBool:
+		return "bool"
+	case ParameterDuration:
+		return "duration"
+	case ParameterEnum:
+		return "enum"
+	case ParameterFile:
+		return "file"
+	default:
+		return "unknown"
+	}
+}
+
+// Parameter denotes a declared flag or positional argument of a command built with CommandBuilder.
+type Parameter struct {
+	name         string
+	short        rune
+	usage        string
+	typ          ParameterType
+	values       []string
+	defaults     []string
+	completion   ArgCompletion
+	isFlag       bool
+	isRequired   bool
+	isRepeatable bool
+}
+
+// Short sets a single character alias for a flag that is used as -s.
+func (p *Parameter) Short(short rune) *Parameter {
+	p.short = short
+	return p
+}
+
+// Required marks the parameter as mandatory.
+func (p *Parameter) Required() *Parameter {
+	p.isRequired = true
+	return p
+}
+
+// Repeated allows the parameter to be passed multiple times. Only the last positional argument can be repeated.
+func (p *Parameter) Repeated() *Parameter {
+	p.isRepeatable = true
+	return p
+}
+
+// Default sets the values that are used when the parameter is not passed. Values are given as on the command line.
+func (p *Parameter) Default(values ...string) *Parameter {
+	p.defaults = values
+	return p
+}
+
+// WithCompletion sets the completion for values of the parameter. Enum and bool parameters complete their values and file parameters the local file system by default.
+func (p *Parameter) WithCompletion(completion ArgCompletion) *Parameter {
+	p.completion = completion
+	return p
+}
+
+// Name returns the name of the parameter.
+func (p *Parameter) Name() string {
+	return p.name
+}
+
+// Type returns the value type of the parameter.
+func (p *Parameter) Type() ParameterType {
+	return p.typ
+}
+
+// parse converts a single value to the parameter type.
+func (p *Parameter) parse(value string) (any, error) {
+	switch p.typ {
+	case ParameterInt:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s expects an integer, got %q", p.displayName(), value)
+		}
+		return i, nil
+	case ParameterBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s expects true or false, got %q", p.displayName(), value)
+		}
+		return b, nil
+	case ParameterDuration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s expects a duration like 1m30s, got %q", p.displayName(), value)
+		}
+		return d, nil
+	case ParameterEnum:
+		for _, v := range p.values {
+			if v == value {
+				return value, nil
+			}
+		}
+		return nil, fmt.Errorf("%s must be one of %s, got %q", p.displayName(), strings.Join(p.values, ", "), value)
+	default:
+		return value, nil
+	}
+}
+
+// displayName returns the name of the parameter as used in error messages.
+func (p *Parameter) displayName() string {
+	if p.isFlag {
+		return "--" + p.name
+	}
+	return p.name
+}
+
+// valueName returns a placeholder for the value of the parameter as used in usage texts.
+func (p *Parameter) valueName() string {
+	if p.typ == ParameterEnum {
+		return strings.Join(p.values, "|")
+	}
+	return p.typ.String()
+}
+
+func (p *Parameter) getCompletionOptions(currentCommand []string, entryIndex int) []CompletionOption {
+	if p.completion != nil {
+		return p.completion.GetCompletionOptions(currentCommand, entryIndex)
+	}
+	return nil
+}
+
+// defaultCompletion returns the completion of parameters of the given type that is used unless WithCompletion is called.
+func defaultCompletion(typ ParameterType, values []string) ArgCompletion {
+	switch typ {
+	case ParameterEnum:
+		return NewOneOfArgCompletion(values...)
+	case ParameterBool:
+		return NewOneOfArgCompletion("false", "true")
+	case ParameterFile:
+		return NewLocalFileSystemArgCompletion(true)
+	default:
+		return nil
+	}
+}
+
+// ParsedArgs holds the values of all declared parameters of a command.
+//
+// Accessing a parameter that has not been declared with the requested type panics.
+type ParsedArgs struct {
+	params map[string]*Parameter
+	values map[string][]any
+	isSet  map[string]bool
+}
+
+func (a *ParsedArgs) get(name string, typ ParameterType) []any {
+	p, ok := a.params[name]
+	if !ok {
+		panic(fmt.Sprintf("commandline: parameter %q is not declared", name))
+	}
+	if p.typ != typ && !((p.typ == ParameterEnum || p.typ == ParameterFile) && typ == ParameterString) {
+		panic(fmt.Sprintf("commandline: parameter %q is of type %s, not %s", name, p.typ, typ))
+	}
+	return a.values[name]
+}
+
+// IsSet returns true if the parameter has been passed on the command line.
+func (a *ParsedArgs) IsSet(name string) bool {
+	return a.isSet[name]
+}
+
+// String returns the last value of a string, enum or file parameter.
+func (a *ParsedArgs) String(name string) string {
+	if values := a.get(name, ParameterString); len(values) > 0 {
+		return values[len(values)-1].(string)
+	}
+	return ""
+}
+
+// Strings returns all values of a string, enum or file parameter.
+func (a *ParsedArgs) Strings(name string) []string {
+	values := a.get(name, ParameterString)
+	result := make([]string, len(values))
+	for i := range values {
+		result[i] = values[i].(string)
+	}
+	return result
+}
+
+// Int returns the last value of an int parameter.
+func (a *ParsedArgs) Int(name string) int {
+	if values := a.get(name, ParameterInt); len(values) > 0 {
+		return values[len(values)-1].(int)
+	}
+	return 0
+}
+
+// Ints returns all values of an int parameter.
+func (a *ParsedArgs) Ints(name string) []int {
+	values := a.get(name, ParameterInt)
+	result := make([]int, len(values))
+	for i := range values {
+		result[i] = values[i].(int)
+	}
+	return result
+}
+
+// Bool returns the last value of a bool parameter.
+func (a *ParsedArgs) Bool(name string) bool {
+	if values := a.get(name, ParameterBool); len(values) > 0 {
+		return values[len(values)-1].(bool)
+	}
+	return false
+}
+
+// Duration returns the last value of a duration parameter.
+func (a *ParsedArgs) Duration(name string) time.Duration {
+	if values := a.get(name, ParameterDuration); len(values) > 0 {
+		return values[len(values)-1].(time.Duration)
+	}
+	return 0
+}
+
+// Durations returns all values of a duration parameter.
+func (a *ParsedArgs) Durations(name string) []time.Duration {
+	values := a.get(name, ParameterDuration)
+	result := make([]time.Duration, len(values))
+	for i := range values {
+		result[i] = values[i].(time.Duration)
+	}
+	return result
+}
+
+// ExecParsedCommandHandler is called with the parsed arguments when processing a command built with CommandBuilder.
+type ExecParsedCommandHandler func(args *ParsedArgs) error
+
+// ExecParsedStreamCommandHandler is called with the parsed arguments and the streams when processing a command built with CommandBuilder.BuildStream.
+type ExecParsedStreamCommandHandler func(args *ParsedArgs, streams Streams) error
+
+// CommandBuilder declares typed flags and positional arguments of a command.
+//
+// Flags are passed as --name value, --name=value, -s value or -s=value. Bool flags do not need a value and short bool flags can be combined like -vq. All arguments after -- are positional.
+type CommandBuilder struct {
+	name  string
+	info  CommandInfo
+	flags []*Parameter
+	args  []*Parameter
+}
+
+// NewCommandBuilder returns a builder for a command with the given name.
+func NewCommandBuilder(name string) *CommandBuilder {
+	return &CommandBuilder{name: name}
+}
+
+// Description sets a one line summary of the command.
+func (b *CommandBuilder) Description(description string) *CommandBuilder {
+	b.info.Description = description
+	return b
+}
+
+// Help sets a detailed explanation of the command.
+func (b *CommandBuilder) Help(help string) *CommandBuilder {
+	b.info.Help = help
+	return b
+}
+
+// Example adds an example command line.
+func (b *CommandBuilder) Example(example string) *CommandBuilder {
+	b.info.Examples = append(b.info.Examples, example)
+	return b
+}
+
+// Category sets the category the command is listed in by the help command.
+func (b *CommandBuilder) Category(category string) *CommandBuilder {
+	b.info.Category = category
+	return b
+}
+
+func (b *CommandBuilder) flag(name, usage string, typ ParameterType, values []string) *Parameter {
+	p := &Parameter{name: name, usage: usage, typ: typ, values: values, completion: defaultCompletion(typ, values), isFlag: true}
+	b.flags = append(b.flags, p)
+	return p
+}
+
+func (b *CommandBuilder) arg(name, usage string, typ ParameterType, values []string) *Parameter {
+	p := &Parameter{name: name, usage: usage, typ: typ, values: values, completion: defaultCompletion(typ, values)}
+	b.args = append(b.args, p)
+	return p
+}
+
+// StringFlag declares a flag with an arbitrary value.
+func (b *CommandBuilder) StringFlag(name, usage string) *Parameter {
+	return b.flag(name, usage, ParameterString, nil)
+}
+
+// IntFlag declares a flag with an integer value.
+func (b *CommandBuilder) IntFlag(name, usage string) *Parameter {
+	return b.flag(name, usage, ParameterInt, nil)
+}
+
+// BoolFlag declares a flag that is switched on by passing it.
+func (b *CommandBuilder) BoolFlag(name, usage string) *Parameter {
+	return b.flag(name, usage, ParameterBool, nil)
+}
+
+// DurationFlag declares a flag with a duration value.
+func (b *CommandBuilder) DurationFlag(name, usage string) *Parameter {
+	return b.flag(name, usage, ParameterDuration, nil)
+}
+
+// EnumFlag declares a flag that accepts one of the given values.
+func (b *CommandBuilder) EnumFlag(name, usage string, values ...string) *Parameter {
+	return b.flag(name, usage, ParameterEnum, values)
+}
+
+// FileFlag declares a flag with a path in the local file system as value, which is completed from the file system.
+func (b *CommandBuilder) FileFlag(name, usage string) *Parameter {
+	return b.flag(name, usage, ParameterFile, nil)
+}
+
+// StringArg declares a positional argument with an arbitrary value.
+func (b *CommandBuilder) StringArg(name, usage string) *Parameter {
+	return b.arg(name, usage, ParameterString, nil)
+}
+
+// IntArg declares a positional argument with an integer value.
+func (b *CommandBuilder) IntArg(name, usage string) *Parameter {
+	return b.arg(name, usage, ParameterInt, nil)
+}
+
+// BoolArg declares a positional argument with a boolean value.
+func (b *CommandBuilder) BoolArg(name, usage string) *Parameter {
+	return b.arg(name, usage, ParameterBool, nil)
+}
+
+// DurationArg declares a positional argument with a duration value.
+func (b *CommandBuilder) DurationArg(name, usage string) *Parameter {
+	return b.arg(name, usage, ParameterDuration, nil)
+}
+
+// EnumArg declares a positional argument that accepts one of the given values.
+func (b *CommandBuilder) EnumArg(name, usage string, values ...string) *Parameter {
+	return b.arg(name, usage, ParameterEnum, values)
+}
+
+// FileArg declares a positional argument with a path in the local file system, which is completed from the file system.
+func (b *CommandBuilder) FileArg(name, usage string) *Parameter {
+	return b.arg(name, usage, ParameterFile, nil)
+}
+
+// Build returns the command that parses its arguments and passes them to handler.
+//
+// Build panics if the declaration is inconsistent, e.g. on duplicate names or invalid defaults.
+func (b *CommandBuilder) Build(handler ExecParsedCommandHandler) Command {
+	c := b.build()
+	c.handler = handler
+	return c
+}
+
+// BuildStream returns a StreamCommand that parses its arguments and passes them to handler together with its streams. It panics like Build.
+func (b *CommandBuilder) BuildStream(handler ExecParsedStreamCommandHandler) Command {
+	return &parsedStreamCommand{parsedCommand: b.build(), handler: handler}
+}
+
+func (b *CommandBuilder) build() *parsedCommand {
+	c := &parsedCommand{
+		name:   b.name,
+		info:   b.info,
+		flags:  b.flags,
+		args:   b.args,
+		params: make(map[string]*Parameter),
+	}
+
+	for i, p := range append(append([]*Parameter{}, b.flags...), b.args...) {
+		if _, exists := c.params[p.name]; exists {
+			panic(fmt.Sprintf("commandline: parameter %q of command %q is declared twice", p.name, b.name))
+		}
+		c.params[p.name] = p
+
+		for _, d := range p.defaults {
+			if _, err := p.parse(d); err != nil {
+				panic(fmt.Sprintf("commandline: invalid default of command %q: %s", b.name, err.Error()))
+			}
+		}
+
+		if !p.isFlag {
+			argIndex := i - len(b.flags)
+			if p.isRepeatable && argIndex < len(b.args)-1 {
+				panic(fmt.Sprintf("commandline: repeated argument %q of command %q must be the last one", p.name, b.name))
+			}
+			if p.isRequired && argIndex > 0 && !b.args[argIndex-1].isRequired {
+				panic(fmt.Sprintf("commandline: required argument %q of command %q follows an optional one", p.name, b.name))
+			}
+		}
+	}
+
+	return c
+}
+
+type parsedCommand struct {
+	name       string
+	parentPath string
+	info       CommandInfo
+	flags      []*Parameter
+	args       []*Parameter
+	params     map[string]*Parameter
+	handler    ExecParsedCommandHandler
+}
+
+func (c *parsedCommand) Name() string {
+	return c.name
+}
+
+func (c *parsedCommand) Description() string {
+	return c.info.Description
+}
+
+func (c *parsedCommand) Help() string {
+	return c.info.Help
+}
+
+func (c *parsedCommand) Examples() []string {
+	return c.info.Examples
+}
+
+func (c *parsedCommand) Category() string {
+	return c.info.Category
+}
+
+func (c *parsedCommand) setParentPath(path string) {
+	c.parentPath = path
+}
+
+func (c *parsedCommand) findFlag(arg string) (*Parameter, bool) {
+	if strings.HasPrefix(arg, "--") {
+		p, ok := c.params[arg[2:]]
+		return p, ok && p.isFlag
+	}
+	r := []rune(arg[1:])
+	if len(r) != 1 {
+		return nil, false
+	}
+	for _, p := range c.flags {
+		if p.short != 0 && p.short == r[0] {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// isFlag returns true if arg is not a positional argument.
+func isFlag(arg string) bool {
+	if len(arg) < 2 || arg[0] != '-' {
+		return false
+	}
+	// negative numbers are positional arguments
+	_, err := strconv.ParseFloat(arg, 64)
+	return err != nil
+}
+
+// Parse parses the given arguments according to the declared parameters. Errors that can be attributed to a single argument are of type ErrInvalidArgument.
+func (c *parsedCommand) Parse(args []string) (*ParsedArgs, error) {
+	result := &ParsedArgs{
+		params: c.params,
+		values: make(map[string][]any),
+		isSet:  make(map[string]bool),
+	}
+
+	set := func(p *Parameter, index int, value string) error {
+		v, err := p.parse(value)
+		if err != nil {
+			return NewErrInvalidArgument(index, err.Error())
+		}
+		if !p.isRepeatable {
+			result.values[p.name] = nil
+		}
+		result.values[p.name] = append(result.values[p.name], v)
+		result.isSet[p.name] = true
+		return nil
+	}
+
+	argIndex := 0
+	flagsEnded := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if !flagsEnded && arg == "--" {
+			flagsEnded = true
+			continue
+		}
+
+		if !flagsEnded && isFlag(arg) {
+			name, value, hasValue := strings.Cut(arg, "=")
+			p, ok := c.findFlag(name)
+			if !ok {
+				if !hasValue && !strings.HasPrefix(arg, "--") && c.setShortBools(result, arg) {
+					continue
+				}
+				return nil, NewErrInvalidArgument(i, fmt.Sprintf("unknown flag %s", name))
+			}
+
+			if !hasValue {
+				if p.typ == ParameterBool {
+					value = "true"
+				} else {
+					if i+1 >= len(args) {
+						return nil, NewErrInvalidArgument(i, fmt.Sprintf("flag %s needs a value", name))
+					}
+					i++
+					value = args[i]
+				}
+			}
+			if err := set(p, i, value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if argIndex >= len(c.args) {
+			return nil, NewErrInvalidArgument(i, "too many arguments")
+		}
+		p := c.args[argIndex]
+		if err := set(p, i, arg); err != nil {
+			return nil, err
+		}
+		if !p.isRepeatable {
+			argIndex++
+		}
+	}
+
+	for _, p := range append(append([]*Parameter{}, c.args...), c.flags...) {
+		if result.isSet[p.name] {
+			continue
+		}
+		if p.isRequired {
+			if p.isFlag {
+				return nil, fmt.Errorf("missing required flag --%s", p.name)
+			}
+			return nil, fmt.Errorf("missing required argument %s", p.name)
+		}
+		for _, d := range p.defaults {
+			// defaults are checked by Build
+			v, _ := p.parse(d)
+			result.values[p.name] = append(result.values[p.name], v)
+		}
+	}
+
+	return result, nil
+}
+
+// setShortBools sets combined short bool flags like -vq and returns false if arg is not a valid combination.
+func (c *parsedCommand) setShortBools(result *ParsedArgs, arg string) bool {
+	flags := make([]*Parameter, 0)
+	for _, r := range arg[1:] {
+		p, ok := c.findFlag("-" + string(r))
+		if !ok || p.typ != ParameterBool {
+			return false
+		}
+		flags = append(flags, p)
+	}
+	for _, p := range flags {
+		result.values[p.name] = []any{true}
+		result.isSet[p.name] = true
+	}
+	return true
+}
+
+func (c *parsedCommand) Validate(args []string) error {
+	_, err := c.Parse(args)
+	return err
+}
+
+func (c *parsedCommand) Exec(args []string) error {
+	parsed, err := c.Parse(args)
+	if err != nil {
+		return ErrInvalidUsage{Err: err, Usage: c.Usage()}
+	}
+	if c.handler != nil {
+		return c.handler(parsed)
+	}
+	return nil
+}
+
+type parsedStreamCommand struct {
+	*parsedCommand
+	handler ExecParsedStreamCommandHandler
+}
+
+func (c *parsedStreamCommand) Exec(args []string) error {
+	return c.ExecStreams(args, ConsoleStreams())
+}
+
+func (c *parsedStreamCommand) ExecStreams(args []string, streams Streams) error {
+	parsed, err := c.Parse(args)
+	if err != nil {
+		return ErrInvalidUsage{Err: err, Usage: c.Usage()}
+	}
+	if c.handler != nil {
+		return c.handler(parsed, streams)
+	}
+	return nil
+}
+
+func (c *parsedCommand) GetCompletionOptions(currentCommand []string, entryIndex int) []CompletionOption {
+	if entryIndex < 1 {
+		return nil
+	}
+	current := currentCommand[entryIndex]
+
+	// replay the preceding arguments to find out what is expected at the current position
+	argIndex := 0
+	flagsEnded := false
+	used := make(map[string]bool)
+	for i := 1; i < entryIndex; i++ {
+		arg := currentCommand[i]
+		if !flagsEnded && arg == "--" {
+			flagsEnded = true
+			continue
+		}
+		if !flagsEnded && isFlag(arg) {
+			name, _, hasValue := strings.Cut(arg, "=")
+			if p, ok := c.findFlag(name); ok {
+				used[p.name] = true
+				if !hasValue && p.typ != ParameterBool {
+					if i+1 == entryIndex {
+						return p.getCompletionOptions(currentCommand, entryIndex)
+					}
+					i++
+				}
+			}
+			continue
+		}
+		if argIndex < len(c.args) && !c.args[argIndex].isRepeatable {
+			argIndex++
+		}
+	}
+
+	if !flagsEnded && strings.HasPrefix(current, "-") {
+		if name, value, hasValue := strings.Cut(current, "="); hasValue {
+			// complete value of --name=value
+			p, ok := c.findFlag(name)
+			if !ok {
+				return nil
+			}
+			valueCommand := append(append([]string{}, currentCommand[:entryIndex]...), value)
+			options := p.getCompletionOptions(valueCommand, entryIndex)
+			prefixed := make([]CompletionOption, len(options))
+			for i, o := range options {
+				prefixed[i] = &completionOption{label: o.String(), replacement: name + "=" + o.Replacement(), description: getDescription(o), isPartial: o.IsPartial()}
+			}
+			return prefixed
+		}
+
+		options := make([]CompletionOption, 0, len(c.flags))
+		for _, p := range c.flags {
+			if !used[p.name] || p.isRepeatable {
+				options = append(options, NewDescribedCompletionOption("--"+p.name, p.usage, false))
+			}
+		}
+		return options
+	}
+
+	if argIndex < len(c.args) {
+		return c.args[argIndex].getCompletionOptions(currentCommand, entryIndex)
+	}
+	return nil
+}
+
+// Usage returns a description of all declared parameters.
+func (c *parsedCommand) Usage() string {
+	var sb strings.Builder
+	sb.WriteString("Usage: ")
+	if len(c.parentPath) > 0 {
+		sb.WriteString(c.parentPath + " ")
+	}
+	sb.WriteString(c.name)
+	if len(c.flags) > 0 {
+		sb.WriteString(" [flags]")
+	}
+	for _, p := range c.args {
+		name := p.name
+		if p.isRepeatable {
+			name += "..."
+		}
+		if p.isRequired {
+			sb.WriteString(" <" + name + ">")
+		} else {
+			sb.WriteString(" [" + name + "]")
+		}
+	}
+	sb.WriteString("\n")
+
+	writeSection := func(title string, params []*Parameter, label func(p *Parameter) string) {
+		if len(params) == 0 {
+			return
+		}
+		labels := make([]string, len(params))
+		labelWidth := 0
+		for i, p := range params {
+			labels[i] = label(p)
+			labelWidth = max(labelWidth, displayWidth(labels[i]))
+		}
+
+		sb.WriteString("\n" + title + ":\n")
+		for i, p := range params {
+			line := "  " + labels[i] + strings.Repeat(" ", labelWidth-displayWidth(labels[i])+listSpaceLen) + p.usage
+			if p.isRequired {
+				line += " (required)"
+			} else if len(p.defaults) > 0 {
+				line += fmt.Sprintf(" (default %s)", strings.Join(p.defaults, ", "))
+			}
+			sb.WriteString(strings.TrimRight(line, " ") + "\n")
+		}
+	}
+
+	writeSection("Arguments", c.args, func(p *Parameter) string {
+		return p.name + " " + p.valueName()
+	})
+
+	flags := append([]*Parameter{}, c.flags...)
+	sort.SliceStable(flags, func(i, j int) bool { return flags[i].name < flags[j].name })
+	writeSection("Flags", flags, func(p *Parameter) string {
+		label := "    --" + p.name
+		if p.short != 0 {
+			label = "-" + string(p.short) + ", --" + p.name
+		}
+		if p.typ != ParameterBool {
+			label += " " + p.valueName()
+		}
+		return label
+	})
+
+	return sb.String()
+}
@@ -0,0 +1,208 @@
+package commandline
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DENICeG/go-console/v2/consoletest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func prepareParsedCommand(handler ExecParsedCommandHandler) *parsedCommand {
+	b := NewCommandBuilder("fetch")
+	b.StringFlag("output", "write result to file").Short('o')
+	b.IntFlag("retries", "number of retries").Default("3")
+	b.BoolFlag("verbose", "print details").Short('v')
+	b.BoolFlag("quiet", "print nothing").Short('q')
+	b.DurationFlag("timeout", "request timeout").Default("5s")
+	b.EnumFlag("format", "output format", "json", "text").Default("text")
+	b.StringFlag("header", "additional header").Short('H').Repeated()
+	b.StringArg("domain", "domain to fetch").Required()
+	b.StringArg("types", "record types").Repeated().WithCompletion(NewOneOfArgCompletion("A", "AAAA", "MX"))
+	return b.Build(handler).(*parsedCommand)
+}
+
+func TestParseArgs(t *testing.T) {
+	c := prepareParsedCommand(nil)
+
+	args, err := c.Parse([]string{"example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", args.String("domain"))
+	assert.Equal(t, 3, args.Int("retries"))
+	assert.Equal(t, 5*time.Second, args.Duration("timeout"))
+	assert.Equal(t, "text", args.String("format"))
+	assert.False(t, args.Bool("verbose"))
+	assert.False(t, args.IsSet("retries"))
+	assert.Empty(t, args.Strings("types"))
+
+	args, err = c.Parse([]string{"-v", "--retries=5", "example.com", "A", "--timeout", "1m", "-H", "a", "-H=b", "MX", "-o", "out.txt", "--format", "json"})
+	require.NoError(t, err)
+	assert.True(t, args.Bool("verbose"))
+	assert.False(t, args.Bool("quiet"))
+	assert.Equal(t, 5, args.Int("retries"))
+	assert.True(t, args.IsSet("retries"))
+	assert.Equal(t, time.Minute, args.Duration("timeout"))
+	assert.Equal(t, []string{"a", "b"}, args.Strings("header"))
+	assert.Equal(t, []string{"A", "MX"}, args.Strings("types"))
+	assert.Equal(t, "out.txt", args.String("output"))
+	assert.Equal(t, "json", args.String("format"))
+
+	args, err = c.Parse([]string{"-vq", "--verbose=false", "--", "-example.com"})
+	require.NoError(t, err)
+	assert.False(t, args.Bool("verbose"))
+	assert.True(t, args.Bool("quiet"))
+	assert.Equal(t, "-example.com", args.String("domain"))
+
+	assert.Panics(t, func() { args.Int("verbose") })
+	assert.Panics(t, func() { args.String("unknown") })
+}
+
+func TestParseArgsErrors(t *testing.T) {
+	c := prepareParsedCommand(nil)
+
+	_, err := c.Parse(nil)
+	assert.EqualError(t, err, "missing required argument domain")
+
+	_, err = c.Parse([]string{"example.com", "--retries", "many"})
+	assert.Equal(t, NewErrInvalidArgument(2, `--retries expects an integer, got "many"`), err)
+
+	_, err = c.Parse([]string{"--format=xml", "example.com"})
+	assert.Equal(t, NewErrInvalidArgument(0, `--format must be one of json, text, got "xml"`), err)
+
+	_, err = c.Parse([]string{"example.com", "--unknown"})
+	assert.Equal(t, NewErrInvalidArgument(1, "unknown flag --unknown"), err)
+
+	_, err = c.Parse([]string{"example.com", "-vx"})
+	assert.Equal(t, NewErrInvalidArgument(1, "unknown flag -vx"), err)
+
+	_, err = c.Parse([]string{"example.com", "--output"})
+	assert.Equal(t, NewErrInvalidArgument(1, "flag --output needs a value"), err)
+
+	b := NewCommandBuilder("add")
+	b.IntArg("a", "").Required()
+	b.IntArg("b", "").Required()
+	c = b.Build(nil).(*parsedCommand)
+	args, err := c.Parse([]string{"-5", "3"})
+	require.NoError(t, err)
+	assert.Equal(t, -5, args.Int("a"))
+	_, err = c.Parse([]string{"1", "2", "3"})
+	assert.Equal(t, NewErrInvalidArgument(2, "too many arguments"), err)
+}
+
+func TestCommandBuilderPanics(t *testing.T) {
+	assert.Panics(t, func() {
+		b := NewCommandBuilder("cmd")
+		b.IntFlag("count", "").Default("many")
+		b.Build(nil)
+	})
+	assert.Panics(t, func() {
+		b := NewCommandBuilder("cmd")
+		b.StringArg("files", "").Repeated()
+		b.StringArg("target", "")
+		b.Build(nil)
+	})
+	assert.Panics(t, func() {
+		b := NewCommandBuilder("cmd")
+		b.StringArg("source", "")
+		b.StringArg("target", "").Required()
+		b.Build(nil)
+	})
+	assert.Panics(t, func() {
+		b := NewCommandBuilder("cmd")
+		b.StringFlag("name", "")
+		b.StringArg("name", "")
+		b.Build(nil)
+	})
+}
+
+func TestParsedCommandCompletion(t *testing.T) {
+	c := prepareParsedCommand(nil)
+
+	options := matchOptions(c.GetCompletionOptions([]string{"fetch", "--f"}, 1), "--f", nil)
+	assert.Equal(t, []string{"--format"}, replacements(options))
+	assert.Equal(t, "output format", getDescription(options[0]))
+
+	// used flags are not completed again unless repeatable
+	options = c.GetCompletionOptions([]string{"fetch", "-v", "--header", "a", "-"}, 4)
+	assert.NotContains(t, replacements(options), "--verbose")
+	assert.Contains(t, replacements(options), "--header")
+
+	assert.Equal(t, []string{"json", "text"}, replacements(c.GetCompletionOptions([]string{"fetch", "--format", ""}, 2)))
+	assert.Equal(t, []string{"--format=json", "--format=text"}, replacements(c.GetCompletionOptions([]string{"fetch", "--format="}, 1)))
+
+	// first positional argument has no completion, all following ones complete record types
+	assert.Empty(t, c.GetCompletionOptions([]string{"fetch", "-v", ""}, 2))
+	assert.Equal(t, []string{"A", "AAAA", "MX"}, replacements(c.GetCompletionOptions([]string{"fetch", "--retries", "1", "example.com", ""}, 4)))
+	assert.Equal(t, []string{"A", "AAAA", "MX"}, replacements(c.GetCompletionOptions([]string{"fetch", "example.com", "A", ""}, 3)))
+}
+
+func TestParsedCommandFileCompletion(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "zone.txt"), nil, 0o600))
+
+	b := NewCommandBuilder("import")
+	b.FileFlag("log", "log file")
+	b.FileArg("file", "file to import").Required()
+	b.BoolArg("dry", "only check the file")
+	c := b.Build(nil).(*parsedCommand)
+
+	file := filepath.Join(dir, "zone.txt")
+	assert.Equal(t, []string{file}, replacements(c.GetCompletionOptions([]string{"import", dir + "/"}, 1)))
+	assert.Equal(t, []string{"--log=" + file}, replacements(c.GetCompletionOptions([]string{"import", "--log=" + dir + "/"}, 1)))
+	assert.Equal(t, []string{"false", "true"}, replacements(c.GetCompletionOptions([]string{"import", "a", ""}, 2)))
+
+	parsed, err := c.Parse([]string{"--log", "out.log", file})
+	require.NoError(t, err)
+	assert.Equal(t, file, parsed.String("file"))
+	assert.Equal(t, "out.log", parsed.String("log"))
+	assert.Contains(t, c.Usage(), "--log file")
+}
+
+func TestParsedCommandUsage(t *testing.T) {
+	b := NewCommandBuilder("copy")
+	b.BoolFlag("force", "overwrite existing files").Short('f')
+	b.IntFlag("depth", "maximum depth").Default("1")
+	b.StringArg("source", "file to copy").Required()
+	b.StringArg("targets", "destinations").Repeated()
+
+	assert.Equal(t, `Usage: copy [flags] <source> [targets...]
+
+Arguments:
+  source string   file to copy (required)
+  targets string  destinations
+
+Flags:
+      --depth int  maximum depth (default 1)
+  -f, --force      overwrite existing files
+`, b.Build(nil).(*parsedCommand).Usage())
+}
+
+func TestCommandLineEnvironmentParsedCommand(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		cle, _, _ := prepareTestCLE()
+		var domain string
+		var retries int
+		cle.RegisterCommand(prepareParsedCommand(func(args *ParsedArgs) error {
+			domain = args.String("domain")
+			retries = args.Int("retries")
+			return nil
+		}))
+
+		input.PutString("fetch --ret\t7 example.com\nexit\n")
+		assert.NoError(t, cle.Run())
+		input.AssertBufferConsumed(t)
+		assert.Equal(t, "example.com", domain)
+		assert.Equal(t, 7, retries)
+
+		// usage is printed by the default error handler
+		err := cle.ExecCommand("fetch", nil)
+		assert.ErrorAs(t, err, &ErrInvalidUsage{})
+		assert.NoError(t, cle.ErrorHandler("fetch", nil, err))
+		assert.True(t, strings.Contains(output.String(), "ERROR: missing required argument domain\nUsage: fetch [flags] <domain> [types...]\n"))
+	})
+}
@@ -0,0 +1,424 @@
+package commandline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	// OperatorPipe connects stdout of a command to stdin of the next one.
+	OperatorPipe = "|"
+	// OperatorRedirectOutput writes stdout of a command to a file.
+	OperatorRedirectOutput = ">"
+	// OperatorAppendOutput appends stdout of a command to a file.
+	OperatorAppendOutput = ">>"
+	// OperatorRedirectInput reads stdin of a command from a file.
+	OperatorRedirectInput = "<"
+)
+
+// pipelineOperators are ordered so that longer operators are matched first.
+var pipelineOperators = []string{OperatorAppendOutput, OperatorRedirectOutput, OperatorRedirectInput, OperatorPipe}
+
+// PipelineCommand denotes a single command of a pipeline with its redirections.
+type PipelineCommand struct {
+	// Args contains the command name followed by its arguments.
+	Args []string
+	// Input denotes a file that is read as stdin, if not empty.
+	Input string
+	// Output denotes a file that receives stdout, if not empty.
+	Output string
+	// Append denotes whether Output is appended to instead of being truncated.
+	Append bool
+	// Globs contains the glob patterns of arguments with unquoted wildcards by their index in Args. Quoted and escaped wildcards are escaped by a backslash.
+	Globs map[int]string
+}
+
+// Pipeline denotes commands that are connected by |.
+type Pipeline struct {
+	Commands []PipelineCommand
+}
+
+// ErrSyntax is returned for command lines with misplaced operators.
+type ErrSyntax struct {
+	// Operator denotes the unexpected operator. It is empty if the command line ended unexpectedly.
+	Operator string
+}
+
+func (e ErrSyntax) Error() string {
+	if len(e.Operator) == 0 {
+		return "syntax error: unexpected end of command line"
+	}
+	return fmt.Sprintf("syntax error near %q", e.Operator)
+}
+
+// lexItem denotes a word or an operator of a command line.
+type lexItem struct {
+	value    string
+	operator string
+	// pattern denotes the glob pattern of words with unquoted wildcards.
+	pattern string
+	// index denotes the position of the token the item has been read from in the command as returned by ParseCommand.
+	index int
+}
+
+// lexCommandLine splits a command line into words and operators. Operators are only recognized in unquoted text and do not need to be separated by spaces.
+//
+// expand is applied to unquoted and double quoted text. Words that are empty after expansion are omitted.
+func lexCommandLine(line string, operators []string, expand func(string) string) ([]lexItem, bool) {
+	if expand == nil {
+		expand = func(str string) string { return str }
+	}
+
+	tokens, isComplete := TokenizeCommand(line)
+	items := make([]lexItem, 0, len(tokens))
+	tokenIndex := 0
+	for _, t := range tokens {
+		var value, pattern strings.Builder
+		isGlob := false
+		writeUnquoted := func(str string) {
+			value.WriteString(str)
+			pattern.WriteString(str)
+			isGlob = isGlob || hasGlobMeta(str)
+		}
+		writeQuoted := func(str string) {
+			value.WriteString(str)
+			pattern.WriteString(escapeGlob(str))
+		}
+		finishWord := func() {
+			if value.Len() > 0 {
+				item := lexItem{value: value.String(), index: tokenIndex}
+				if isGlob {
+					item.pattern = pattern.String()
+				}
+				items = append(items, item)
+			}
+			value.Reset()
+			pattern.Reset()
+			isGlob = false
+		}
+
+		for _, s := range t.Segments {
+			switch s.Kind {
+			case SegmentPlain:
+				text := s.Value
+				for len(text) > 0 {
+					index, operator := indexOperator(text, operators)
+					if index < 0 {
+						writeUnquoted(expand(text))
+						break
+					}
+					writeUnquoted(expand(text[:index]))
+					finishWord()
+					items = append(items, lexItem{operator: operator, index: tokenIndex})
+					text = text[index+len(operator):]
+				}
+			case SegmentDoubleQuoted:
+				writeQuoted(expand(s.Value))
+			default:
+				writeQuoted(s.Value)
+			}
+		}
+		finishWord()
+		if len(t.Value) > 0 {
+			tokenIndex++
+		}
+	}
+	return items, isComplete
+}
+
+// indexOperator returns the position of the first operator in text or -1.
+func indexOperator(text string, operators []string) (int, string) {
+	for i := 0; i < len(text); i++ {
+		for _, operator := range operators {
+			if strings.HasPrefix(text[i:], operator) {
+				return i, operator
+			}
+		}
+	}
+	return -1, ""
+}
+
+// ParsePipeline parses a command line with pipes and redirections like list | grep pending > out.txt. Quoted or escaped operators are part of the arguments.
+//
+// An empty command line results in a pipeline without commands.
+func ParsePipeline(line string) (*Pipeline, error) {
+	return parsePipeline(line, nil)
+}
+
+func parsePipeline(line string, expand func(string) string) (*Pipeline, error) {
+	items, isComplete := lexCommandLine(line, pipelineOperators, expand)
+	if !isComplete {
+		return nil, fmt.Errorf("unterminated quote or escape sequence")
+	}
+	return newPipeline(items)
+}
+
+// newPipeline assembles a pipeline from words and pipeline operators.
+func newPipeline(items []lexItem) (*Pipeline, error) {
+	p := &Pipeline{Commands: make([]PipelineCommand, 0)}
+	if len(items) == 0 {
+		return p, nil
+	}
+
+	current := PipelineCommand{Args: make([]string, 0)}
+	for i := 0; i < len(items); i++ {
+		item := items[i]
+		switch item.operator {
+		case "":
+			if len(item.pattern) > 0 {
+				if current.Globs == nil {
+					current.Globs = make(map[int]string)
+				}
+				current.Globs[len(current.Args)] = item.pattern
+			}
+			current.Args = append(current.Args, item.value)
+
+		case OperatorPipe:
+			if len(current.Args) == 0 {
+				return nil, ErrSyntax{Operator: item.operator}
+			}
+			p.Commands = append(p.Commands, current)
+			current = PipelineCommand{Args: make([]string, 0)}
+
+		case OperatorRedirectOutput, OperatorAppendOutput, OperatorRedirectInput:
+			if i+1 >= len(items) {
+				return nil, ErrSyntax{}
+			}
+			if len(items[i+1].operator) > 0 {
+				return nil, ErrSyntax{Operator: items[i+1].operator}
+			}
+			i++
+			if item.operator == OperatorRedirectInput {
+				current.Input = items[i].value
+			} else {
+				current.Output = items[i].value
+				current.Append = item.operator == OperatorAppendOutput
+			}
+
+		default:
+			return nil, ErrSyntax{Operator: item.operator}
+		}
+	}
+
+	if len(current.Args) == 0 {
+		if len(p.Commands) > 0 {
+			return nil, ErrSyntax{}
+		}
+		// redirections without command
+		return nil, ErrSyntax{Operator: items[0].operator}
+	}
+	p.Commands = append(p.Commands, current)
+	return p, nil
+}
+
+// execPipeline executes all commands of p concurrently. The first command reads from streams.Stdin and the last one writes to streams.Stdout unless redirected.
+//
+// The error of the last command is returned, or the first error of any other command. Commands that failed to write because a following command stopped reading are not considered as failed.
+// The returned command denotes the one that caused the error.
+func (b *Environment) execPipeline(ctx context.Context, p *Pipeline, streams Streams) ([]string, error) {
+	if len(p.Commands) == 0 {
+		return nil, nil
+	}
+	p, err := b.expandGlobs(p)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.Commands) == 1 && len(p.Commands[0].Input) == 0 && len(p.Commands[0].Output) == 0 {
+		// a sole command without redirection needs no pipes, commands without stream support use the console directly
+		cmd := p.Commands[0].Args
+		return cmd, b.execStreams(ctx, cmd, &streams)
+	}
+
+	if cmd, err := b.requireStreams(p); err != nil {
+		return cmd, err
+	}
+	stageStreams, closers, err := openPipelineStreams(p, streams)
+	if err != nil {
+		return p.Commands[0].Args, err
+	}
+
+	errs := make([]error, len(p.Commands))
+	var wg sync.WaitGroup
+	for i := range p.Commands {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = b.execStreams(ctx, p.Commands[i].Args, &stageStreams[i])
+			// signal end of input to the next command and stop the previous one
+			for _, c := range closers[i] {
+				c.Close() //nolint
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	last := len(p.Commands) - 1
+	for i, err := range errs {
+		if errors.Is(err, ErrExit) {
+			return p.Commands[i].Args, err
+		}
+	}
+	if errs[last] != nil {
+		return p.Commands[last].Args, errs[last]
+	}
+	for i, err := range errs[:last] {
+		if err != nil && !isBrokenPipe(err) {
+			return p.Commands[i].Args, err
+		}
+	}
+	return p.Commands[last].Args, nil
+}
+
+// openPipelineStreams returns the streams of all pipeline commands and the closers that need to be called after each command has finished.
+func openPipelineStreams(p *Pipeline, streams Streams) ([]Streams, [][]io.Closer, error) {
+	stageStreams := make([]Streams, len(p.Commands))
+	closers := make([][]io.Closer, len(p.Commands))
+	closeAll := func() {
+		for _, list := range closers {
+			for _, c := range list {
+				c.Close() //nolint
+			}
+		}
+	}
+
+	var stdin io.Reader = streams.Stdin
+	for i, c := range p.Commands {
+		stageStreams[i] = Streams{Stdin: stdin, Stdout: streams.Stdout, Stderr: streams.Stderr}
+
+		if i < len(p.Commands)-1 {
+			pr, pw := io.Pipe()
+			stageStreams[i].Stdout = pw
+			closers[i] = append(closers[i], pw)
+			closers[i+1] = append(closers[i+1], pr)
+			stdin = pr
+		}
+
+		if len(c.Input) > 0 {
+			if i > 0 {
+				// output of the previous command is discarded
+				stdin.(io.Closer).Close() //nolint
+			}
+			f, err := os.Open(c.Input)
+			if err != nil {
+				closeAll()
+				return nil, nil, err
+			}
+			stageStreams[i].Stdin = f
+			closers[i] = append(closers[i], f)
+		}
+
+		if len(c.Output) > 0 {
+			flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+			if c.Append {
+				flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+			}
+			f, err := os.OpenFile(c.Output, flags, 0o644)
+			if err != nil {
+				closeAll()
+				return nil, nil, err
+			}
+			stageStreams[i].Stdout = f
+			closers[i] = append(closers[i], f)
+		}
+	}
+	return stageStreams, closers, nil
+}
+
+// requireStreams returns an error for the first command of p that does not implement StreamCommand, unless p consists of a sole command without redirection.
+//
+// Output of such commands cannot be redirected, because replacing the console output would also affect concurrent commands like background jobs.
+func (b *Environment) requireStreams(p *Pipeline) ([]string, error) {
+	if len(p.Commands) == 1 && len(p.Commands[0].Input) == 0 && len(p.Commands[0].Output) == 0 {
+		return nil, nil
+	}
+	for _, c := range p.Commands {
+		if !b.supportsStreams(c.Args) {
+			return c.Args, fmt.Errorf("%s does not support streams and cannot be used in pipelines or redirected", c.Args[0])
+		}
+	}
+	return nil, nil
+}
+
+// supportsStreams returns true if cmd denotes a command that implements StreamCommand. For command groups, the selected child command needs to implement it.
+func (b *Environment) supportsStreams(cmd []string) bool {
+	expanded, err := b.expandAliases(cmd)
+	if err != nil || len(expanded) == 0 {
+		// errors are reported on execution
+		return true
+	}
+	c, exists := b.command(expanded[0])
+	if !exists {
+		return false
+	}
+	_, ok := asStreamCommand(leafCommand(c, expanded[1:]))
+	return ok
+}
+
+// commandLineStage denotes the arguments of a single command within a command line.
+type commandLineStage struct {
+	args []string
+	// patterns contains the glob pattern of every argument with unquoted wildcards and is empty for all others.
+	patterns []string
+	// items contains the position of every argument in the lexed command line.
+	items []int
+}
+
+// splitStages splits a lexed command line at pipe and list operators and removes redirections. The positions of redirection targets are returned separately.
+func splitStages(items []lexItem) ([]commandLineStage, map[int]bool) {
+	stages := []commandLineStage{{}}
+	redirectTargets := make(map[int]bool)
+	for i := 0; i < len(items); i++ {
+		switch items[i].operator {
+		case "":
+			current := &stages[len(stages)-1]
+			current.args = append(current.args, items[i].value)
+			current.patterns = append(current.patterns, items[i].pattern)
+			current.items = append(current.items, i)
+		case OperatorRedirectOutput, OperatorAppendOutput, OperatorRedirectInput:
+			if i+1 < len(items) && len(items[i+1].operator) == 0 {
+				i++
+				redirectTargets[i] = true
+			}
+		default:
+			stages = append(stages, commandLineStage{})
+		}
+	}
+	return stages, redirectTargets
+}
+
+// stageAt returns the command of the stage that contains the part at entryIndex of the command as returned by ParseCommand for line, and the index of the entry within that command.
+// A part behind the command line is considered as empty, e.g. when a new part has been started by whitespace.
+//
+// The arguments of the returned stage are empty if entryIndex denotes an operator or a redirection target, which is signaled by isRedirectTarget.
+func stageAt(line string, entryIndex int) (stage commandLineStage, stageIndex int, isRedirectTarget bool) {
+	items, _ := lexCommandLine(line, commandLineOperators, nil)
+	if len(items) == 0 || items[len(items)-1].index < entryIndex {
+		items = append(items, lexItem{index: entryIndex})
+	}
+
+	// operators and words of the same token belong to the last item
+	position := -1
+	for i, item := range items {
+		if item.index == entryIndex {
+			position = i
+		}
+	}
+
+	stages, redirectTargets := splitStages(items)
+	if redirectTargets[position] {
+		return commandLineStage{}, 0, true
+	}
+	for _, s := range stages {
+		for i, p := range s.items {
+			if p == position {
+				return s, i, false
+			}
+		}
+	}
+	return commandLineStage{}, 0, false
+}
@@ -0,0 +1,216 @@
+package commandline
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DENICeG/go-console/v2"
+	"github.com/DENICeG/go-console/v2/consoletest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePipeline(t *testing.T) {
+	p, err := ParsePipeline(`list --all | grep "a | b" 'x>y' a\|b|wc -l>>out.txt < in.txt`)
+	require.NoError(t, err)
+	assert.Equal(t, []PipelineCommand{
+		{Args: []string{"list", "--all"}},
+		{Args: []string{"grep", "a | b", "x>y", "a|b"}},
+		{Args: []string{"wc", "-l"}, Input: "in.txt", Output: "out.txt", Append: true},
+	}, p.Commands)
+
+	p, err = ParsePipeline("  ")
+	require.NoError(t, err)
+	assert.Empty(t, p.Commands)
+
+	for line, expected := range map[string]string{
+		"| grep x":      `syntax error near "|"`,
+		"list || grep":  `syntax error near "|"`,
+		"list |":        "syntax error: unexpected end of command line",
+		"list >":        "syntax error: unexpected end of command line",
+		"list > | grep": `syntax error near "|"`,
+		"> out.txt":     `syntax error near ">"`,
+		"list 'x":       "unterminated quote or escape sequence",
+	} {
+		_, err := ParsePipeline(line)
+		assert.EqualError(t, err, expected, line)
+	}
+}
+
+func preparePipelineCLE() *Environment {
+	cle := NewEnvironment()
+	cle.RegisterCommand(NewStreamCommand("list", nil, func(_ []string, streams Streams) error {
+		for _, domain := range []string{"a.de pending", "b.de active", "c.de pending"} {
+			if _, err := fmt.Fprintln(streams.Stdout, domain); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	cle.RegisterCommand(NewStreamCommand("upper", nil, func(_ []string, streams Streams) error {
+		data, err := io.ReadAll(streams.Stdin)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(streams.Stdout, strings.ToUpper(string(data)))
+		return err
+	}))
+	cle.RegisterCommand(NewGrepCommand("grep"))
+	cle.RegisterCommand(NewHeadCommand("head"))
+	cle.RegisterCommand(NewWcCommand("wc"))
+	return cle
+}
+
+func TestExecLinePipeline(t *testing.T) {
+	consoletest.WithOutputMocks(func(_ *consoletest.MockInput, output *consoletest.MockOutput) {
+		cle := preparePipelineCLE()
+
+		require.NoError(t, cle.ExecLine("list | grep pending | upper"))
+		assert.Equal(t, "A.DE PENDING\nC.DE PENDING\n", output.String())
+		assert.Same(t, output, console.DefaultOutput)
+
+		output.Reset()
+		cle.SetAlias("pending", "grep", "pending")
+		require.NoError(t, cle.ExecLine("list|pending|wc -l"))
+		assert.Equal(t, "2\n", output.String())
+
+		// commands without stream support only write to the console
+		output.Reset()
+		cle.RegisterCommand(NewCustomCommand("print", nil, func(args []string) error {
+			_, err := console.Println(args)
+			return err
+		}))
+		cmd, err := cle.execLine(context.Background(), "list | print x | wc")
+		assert.EqualError(t, err, "print does not support streams and cannot be used in pipelines or redirected")
+		assert.Equal(t, []string{"print", "x"}, cmd)
+		assert.EqualError(t, cle.ExecLine("print x > "+filepath.Join(t.TempDir(), "out.txt")), "print does not support streams and cannot be used in pipelines or redirected")
+		assert.Empty(t, output.String())
+		assert.Same(t, output, console.DefaultOutput)
+	})
+}
+
+func TestExecLineRedirection(t *testing.T) {
+	consoletest.WithOutputMocks(func(_ *consoletest.MockInput, output *consoletest.MockOutput) {
+		cle := preparePipelineCLE()
+		dir := t.TempDir()
+		out := filepath.Join(dir, "out.txt")
+		cle.SetVar("OUT", out)
+
+		require.NoError(t, cle.ExecLine("list > $OUT"))
+		require.NoError(t, cle.ExecLine("list | grep b.de >> $OUT"))
+		assert.Empty(t, output.String())
+		data, err := os.ReadFile(out)
+		require.NoError(t, err)
+		assert.Equal(t, "a.de pending\nb.de active\nc.de pending\nb.de active\n", string(data))
+
+		require.NoError(t, cle.ExecLine("upper < $OUT | head -n 1"))
+		assert.Equal(t, "A.DE PENDING\n", output.String())
+
+		err = cle.ExecLine("upper < " + filepath.Join(dir, "missing.txt"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestExecLinePipelineEarlyExit(t *testing.T) {
+	consoletest.WithOutputMocks(func(_ *consoletest.MockInput, output *consoletest.MockOutput) {
+		cle := preparePipelineCLE()
+		cle.RegisterCommand(NewStreamCommand("yes", nil, func(_ []string, streams Streams) error {
+			for {
+				if _, err := io.WriteString(streams.Stdout, "y\n"); err != nil {
+					return err
+				}
+			}
+		}))
+		cle.RegisterCommand(NewStreamCommand("fail", nil, func([]string, Streams) error {
+			return fmt.Errorf("failed")
+		}))
+
+		// writing to a closed pipe is not an error
+		require.NoError(t, cle.ExecLine("yes | head -n 2"))
+		assert.Equal(t, "y\ny\n", output.String())
+
+		cmd, err := cle.execLine(context.Background(), "fail x | upper")
+		assert.EqualError(t, err, "failed")
+		assert.Equal(t, []string{"fail", "x"}, cmd)
+	})
+}
+
+func TestExecLineStdin(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		cle := preparePipelineCLE()
+
+		input.PutString("foo\nbar\n")
+		require.NoError(t, cle.ExecLine("upper | wc"))
+		assert.Equal(t, "2 2 8\n", output.String())
+	})
+}
+
+func TestPipelineCompletionAndValidation(t *testing.T) {
+	cle := preparePipelineCLE()
+
+	options, _ := cle.CompleteCommandLine("list | gr", []string{"list", "|", "gr"})
+	assert.Contains(t, optionReplacements(options), "grep")
+
+	options, _ = cle.CompleteCommandLine("list|grep -", []string{"list|grep", "-"})
+	assert.Contains(t, optionReplacements(options), "--count")
+
+	// quoted operators are arguments
+	options, _ = cle.CompleteCommandLine(`grep "|" -`, []string{"grep", "|", "-"})
+	assert.Contains(t, optionReplacements(options), "--count")
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "out.txt"), nil, 0o600))
+	options, _ = cle.CompleteCommandLine("list > "+dir+"/o", []string{"list", ">", dir + "/o"})
+	assert.Equal(t, []string{dir + "/out.txt"}, optionReplacements(options))
+
+	// operators in completed file names are escaped
+	name := dir + "/a|b;c&d<e>.txt"
+	require.NoError(t, os.WriteFile(name, nil, 0o600))
+	options, _ = cle.CompleteCommandLine("list > "+dir+"/a", []string{"list", ">", dir + "/a"})
+	require.Equal(t, []string{name}, optionReplacements(matchOptions(options, dir+"/a", nil)))
+	line, ok := completeCommandPart("list > "+dir+"/a", dir+"/a", len("list > "), name, false)
+	require.True(t, ok)
+	assert.Equal(t, "list > "+dir+`/a\|b\;c\&d\<e\>.txt`, line)
+	l, err := ParseCommandList(line)
+	require.NoError(t, err)
+	require.Len(t, l.Items, 1)
+	assert.Equal(t, []PipelineCommand{{Args: []string{"list"}, Output: name}}, l.Items[0].Pipeline.Commands)
+
+	assert.NoError(t, cle.ValidateCommandLine("list | grep x > out.txt"))
+	assert.NoError(t, cle.ValidateCommandLine(`list | grep "|"`))
+	assert.Equal(t, NewErrInvalidArgument(3, "syntax error: unexpected end of command line"), cle.ValidateCommandLine("list | grep |"))
+	assert.Equal(t, NewErrInvalidArgument(2, `syntax error near "|"`), cle.ValidateCommandLine("list > | grep"))
+	assert.Equal(t, NewErrInvalidArgument(5, "unknown flag --foo"), cle.ValidateCommandLine("list | head < in.txt --foo"))
+	err = cle.ValidateCommandLine("list | head -n x")
+	var errArg ErrInvalidArgument
+	require.ErrorAs(t, err, &errArg)
+	assert.Equal(t, 4, errArg.Index)
+}
+
+func TestHighlightPipeline(t *testing.T) {
+	cle := preparePipelineCLE()
+
+	line := "list | grep '|' > out"
+	tokens, _ := TokenizeCommand(line)
+	assert.Equal(t, []Span{
+		{Start: 0, End: 4, Style: styleKnownCommand},
+		{Start: 5, End: 6, Style: styleOperator},
+		{Start: 7, End: 11, Style: styleKnownCommand},
+		{Start: 12, End: 15, Style: styleQuoted},
+		{Start: 16, End: 17, Style: styleOperator},
+	}, cle.HighlightCommand(line, tokens))
+}
+
+func optionReplacements(options []CompletionOption) []string {
+	replacements := make([]string, len(options))
+	for i, o := range options {
+		replacements[i] = o.Replacement()
+	}
+	return replacements
+}
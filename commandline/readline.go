@@ -39,7 +39,13 @@ func (h *memoryLineHistory) GetHistoryEntry(index int) (string, bool) {
 }
 
 // ReadLineWithHistory reads a line from Stdin and allows to select previous options using the Up and Down keys.
+//
+// The history is not available if the console is not interactive.
 func ReadLineWithHistory(history LineHistory) (string, error) {
+	if !console.IsInteractive() {
+		return console.ReadLine()
+	}
+
 	if err := console.BeginReadKey(); err != nil {
 		return "", err
 	}
@@ -50,13 +56,8 @@ func ReadLineWithHistory(history LineHistory) (string, error) {
 
 func readLineWithHistory(history LineHistory) (string, error) {
 	opts := ReadCommandOptions{
-		GetHistoryEntry: func(index int) ([]string, bool) {
-			if line, ok := history.GetHistoryEntry(index); ok {
-				return []string{line}, true
-			}
-			return nil, false
-		},
+		GetHistoryLine: history.GetHistoryEntry,
 	}
 
-	return readCommandLine(nil, "", false, &opts)
+	return readCommandLine(nil, "", &opts)
 }
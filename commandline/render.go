@@ -0,0 +1,352 @@
+package commandline
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/DENICeG/go-console/v2"
+
+	"github.com/rivo/uniseg"
+)
+
+const (
+	ansiClearToEnd = "\x1b[J"
+	ansiReset      = "\x1b[0m"
+)
+
+// lineRenderer draws a prompt and the line currently edited and keeps track of the terminal cursor to be able to redraw both.
+//
+// All cursor movements are relative to the position where rendering started, so the renderer also works with prompts it did not print itself.
+type lineRenderer struct {
+	// prompt is printed in front of the line and may contain ANSI escape sequences.
+	prompt string
+	// rightPrompt is printed right-aligned in the first row as long as there is enough space.
+	rightPrompt string
+	// cursorRow and cursorCol denote the current cursor position relative to the start of the prompt.
+	cursorRow, cursorCol int
+	// endRow denotes the last row that has been rendered relative to the start of the prompt.
+	endRow int
+	// last denotes the most recently rendered frame, which is redrawn after printing above the prompt.
+	last renderFrame
+	// suspended is true after NewLine until the next call to Render.
+	suspended bool
+	mutex     sync.Mutex
+}
+
+func newLineRenderer(prompt string) *lineRenderer {
+	return &lineRenderer{prompt: prompt}
+}
+
+// renderFrame denotes everything that is drawn for the edited line.
+type renderFrame struct {
+	// line is the styled text in front of the cursor.
+	line string
+	// suffix is printed behind the cursor.
+	suffix string
+	// below contains additional lines printed below the line, e.g. for messages.
+	below []string
+}
+
+// Render redraws prompt and frame and places the cursor at the end of the line.
+func (r *lineRenderer) Render(f renderFrame) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.render(f)
+}
+
+// PrintAbove prints text in front of the prompt and redraws the last frame below it. Missing line breaks are appended.
+//
+// Text is printed unchanged after NewLine, e.g. while options are listed.
+func (r *lineRenderer) PrintAbove(text string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.suspended {
+		// prompt is not displayed
+		rawOutput().Print(text) //nolint
+		return
+	}
+
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\n", "\r\n")
+	if !strings.HasSuffix(text, "\r\n") {
+		text += "\r\n"
+	}
+
+	var sb strings.Builder
+	r.writeReturn(&sb)
+	sb.WriteString(ansiClearToEnd)
+	sb.WriteString(text)
+	rawOutput().Print(sb.String()) //nolint
+
+	r.cursorRow, r.cursorCol, r.endRow = 0, 0, 0
+	r.render(r.last)
+}
+
+func (r *lineRenderer) render(f renderFrame) {
+	r.last = f
+	r.suspended = false
+	width := terminalWidth()
+
+	var sb strings.Builder
+	r.writeReturn(&sb)
+	sb.WriteString(ansiClearToEnd)
+
+	sb.WriteString(r.prompt)
+	if strings.ContainsRune(r.prompt, '\x1b') {
+		// do not let prompt colors bleed into the line
+		sb.WriteString(ansiReset)
+	}
+	row, col := advance(r.prompt, width, 0, 0)
+	sb.WriteString(f.line)
+	row, col = advance(f.line, width, row, col)
+	caretRow, caretCol := row, col
+	if width > 0 && caretCol >= width {
+		// next char will be printed in the following line
+		caretRow++
+		caretCol = 0
+	}
+
+	sb.WriteString(f.suffix)
+	row, col = advance(f.suffix, width, row, col)
+
+	if rightPromptWidth := displayWidth(r.rightPrompt); rightPromptWidth > 0 && width > 0 && row == 0 {
+		// keep the last column free to prevent wrapping and at least one space to the input
+		if start := width - rightPromptWidth - 1; col < start {
+			sb.WriteString(cursorForward(start - col))
+			sb.WriteString(r.rightPrompt)
+			if strings.ContainsRune(r.rightPrompt, '\x1b') {
+				sb.WriteString(ansiReset)
+			}
+			col = start + rightPromptWidth
+		}
+	}
+
+	for _, line := range f.below {
+		row, col = writeLineBreak(&sb, width, row, col)
+		sb.WriteString(line)
+		row, col = advance(line, width, row, col)
+	}
+
+	if width > 0 && col >= width {
+		// the terminal either wrapped already or waits for the next char to do so -> enforce a defined state
+		sb.WriteString(" \r")
+		row++
+		col = 0
+	}
+
+	r.endRow = row
+	r.writeMove(&sb, row, col, caretRow, caretCol)
+	rawOutput().Print(sb.String()) //nolint
+}
+
+// Column returns the column in which the cursor would be placed after printing the prompt and the given part of the line.
+func (r *lineRenderer) Column(line string) int {
+	width := terminalWidth()
+	_, col := advance(r.prompt+line, width, 0, 0)
+	if width > 0 && col >= width {
+		return 0
+	}
+	return col
+}
+
+// NewLine moves the cursor below the rendered output. The next call to Render will start in a new line.
+func (r *lineRenderer) NewLine() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	rawOutput().Print(cursorDown(r.endRow-r.cursorRow) + "\r\n") //nolint
+	r.cursorRow, r.cursorCol, r.endRow = 0, 0, 0
+	r.suspended = true
+}
+
+// writeLineBreak starts a new line and returns the new cursor position.
+func writeLineBreak(sb *strings.Builder, width, row, col int) (int, int) {
+	if width > 0 && col >= width {
+		// the terminal either wrapped already or waits for the next char to do so -> a single char defines the state
+		sb.WriteString(" \r")
+	} else {
+		sb.WriteString("\r\n")
+	}
+	return row + 1, 0
+}
+
+// writeReturn moves the cursor back to the position where rendering started.
+func (r *lineRenderer) writeReturn(sb *strings.Builder) {
+	sb.WriteString(cursorUp(r.cursorRow))
+	sb.WriteString(cursorBack(r.cursorCol))
+}
+
+// writeMove moves the cursor from the given position to the target position and remembers the new position.
+func (r *lineRenderer) writeMove(sb *strings.Builder, row, col, targetRow, targetCol int) {
+	sb.WriteString(cursorUp(row - targetRow))
+	sb.WriteString(cursorDown(targetRow - row))
+	sb.WriteString(cursorBack(col - targetCol))
+	sb.WriteString(cursorForward(targetCol - col))
+	r.cursorRow, r.cursorCol = targetRow, targetCol
+}
+
+func terminalWidth() int {
+	width, _ := terminalSize()
+	return width
+}
+
+// terminalSize returns the terminal dimensions or 0 for unknown values.
+func terminalSize() (int, int) {
+	width, height, err := console.GetSize()
+	if err != nil {
+		// unknown terminal size -> do not expect any line wrapping
+		return 0, 0
+	}
+	return max(width, 0), max(height, 0)
+}
+
+// advance returns the cursor position after printing str at the given position on a terminal with the given width.
+//
+// A width of 0 denotes an unlimited line length. The returned column equals width when the line is full, but has not wrapped yet.
+func advance(str string, width int, row, col int) (int, int) {
+	forEachCluster(str, func(_ string, w int) {
+		if width > 0 && col+w > width {
+			// the terminal wraps before printing a char that would exceed the line
+			row++
+			col = 0
+		}
+		col += w
+	})
+	return row, col
+}
+
+// displayWidth returns the number of terminal cells required to print str, ignoring ANSI escape sequences.
+func displayWidth(str string) int {
+	width := 0
+	forEachCluster(str, func(_ string, w int) {
+		width += w
+	})
+	return width
+}
+
+// forEachCluster calls f for every grapheme cluster in str with its display width. ANSI escape sequences are passed with a width of 0.
+func forEachCluster(str string, f func(cluster string, width int)) {
+	state := -1
+	for len(str) > 0 {
+		if n := ansiSequenceLen(str); n > 0 {
+			f(str[:n], 0)
+			str = str[n:]
+			state = -1
+			continue
+		}
+
+		var cluster string
+		var width int
+		cluster, str, width, state = uniseg.FirstGraphemeClusterInString(str, state)
+		f(cluster, width)
+	}
+}
+
+// ansiSequenceLen returns the length in bytes of the ANSI escape sequence at the beginning of str or 0.
+func ansiSequenceLen(str string) int {
+	if len(str) < 2 || str[0] != '\x1b' {
+		return 0
+	}
+
+	switch str[1] {
+	case '[':
+		// CSI sequence: parameter and intermediate bytes are terminated by a single final byte
+		for i := 2; i < len(str); i++ {
+			if str[i] >= 0x40 && str[i] <= 0x7e {
+				return i + 1
+			}
+		}
+		return len(str)
+
+	case ']':
+		// OSC sequence: terminated by BEL or ST
+		for i := 2; i < len(str); i++ {
+			if str[i] == '\a' {
+				return i + 1
+			}
+			if str[i] == '\x1b' && i+1 < len(str) && str[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return len(str)
+
+	default:
+		return 2
+	}
+}
+
+// truncateToWidth shortens str to the given display width and indicates truncation by an ellipsis.
+func truncateToWidth(str string, width int) string {
+	if displayWidth(str) <= width {
+		return str
+	}
+	if width <= 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	current := 0
+	hasStyle := false
+	forEachCluster(str, func(cluster string, w int) {
+		if w == 0 {
+			// keep escape sequences to not break styles
+			sb.WriteString(cluster)
+			hasStyle = hasStyle || strings.HasPrefix(cluster, "\x1b")
+			return
+		}
+		if current+w <= width-1 {
+			sb.WriteString(cluster)
+			current += w
+		} else {
+			current = width
+		}
+	})
+
+	// ellipsis is placed behind the last visible cluster
+	result := sb.String()
+	if hasStyle {
+		return result + ansiReset + "…"
+	}
+	return result + "…"
+}
+
+// removeLastCluster returns str without the last grapheme cluster.
+func removeLastCluster(str string) string {
+	last := 0
+	state := -1
+	rest := str
+	for len(rest) > 0 {
+		last = len(str) - len(rest)
+		_, rest, _, state = uniseg.FirstGraphemeClusterInString(rest, state)
+	}
+	return str[:last]
+}
+
+func cursorUp(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("\x1b[%dA", n)
+}
+
+func cursorDown(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("\x1b[%dB", n)
+}
+
+func cursorForward(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("\x1b[%dC", n)
+}
+
+func cursorBack(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("\x1b[%dD", n)
+}
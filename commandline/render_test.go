@@ -0,0 +1,118 @@
+package commandline
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DENICeG/go-console/v2/consoletest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisplayWidth(t *testing.T) {
+	assert.Equal(t, 6, displayWidth("foobar"))
+	assert.Equal(t, 5, displayWidth("\x1b[32mcle> \x1b[0m"))
+	assert.Equal(t, 4, displayWidth("日本"))
+	assert.Equal(t, 1, displayWidth("é"))
+	assert.Equal(t, 2, displayWidth("👍🏽"))
+	assert.Equal(t, 3, displayWidth("\x1b]0;title\aabc"))
+}
+
+func TestAdvance(t *testing.T) {
+	row, col := advance("foobar", 0, 0, 0)
+	assert.Equal(t, 0, row)
+	assert.Equal(t, 6, col)
+
+	row, col = advance("foobar", 3, 0, 0)
+	assert.Equal(t, 1, row)
+	assert.Equal(t, 3, col)
+
+	row, col = advance("foobar", 4, 0, 2)
+	assert.Equal(t, 1, row)
+	assert.Equal(t, 4, col)
+
+	// wide chars that do not fit at the end of a line are moved to the next one
+	row, col = advance("ab日", 3, 0, 0)
+	assert.Equal(t, 1, row)
+	assert.Equal(t, 2, col)
+}
+
+func TestRemoveLastCluster(t *testing.T) {
+	assert.Equal(t, "", removeLastCluster(""))
+	assert.Equal(t, "foo", removeLastCluster("foob"))
+	assert.Equal(t, "foo", removeLastCluster("fooé"))
+	assert.Equal(t, "日", removeLastCluster("日本"))
+}
+
+func TestReadCommandWrappedLine(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		output.Width = 10
+		input.PutString("foo barbazbla\r\r\n")
+		cmd, err := ReadCommand("cle", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"foo", "barbazb"}, cmd)
+		input.AssertBufferConsumed(t)
+
+		// "cle> foo barbazbla" spans two lines and needs to return to the first one for redraws
+		assert.True(t, strings.Contains(output.String(), cursorUp(1)))
+		assert.True(t, strings.HasSuffix(output.String(), "\r\n"))
+	})
+}
+
+func TestReadCommandWideChars(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		input.PutString("日本\r語\n")
+		cmd, err := ReadCommand("", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"日語"}, cmd)
+		input.AssertBufferConsumed(t)
+
+		// cursor is moved back by the display width of "> 日本"
+		assert.True(t, strings.Contains(output.String(), cursorBack(6)))
+	})
+}
+
+func TestReadCommandPromptSuffix(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		input.PutString("foo\n")
+		_, err := ReadCommand("\x1b[34mfirst\nsecond\x1b[0m", &ReadCommandOptions{PromptSuffix: " $ "})
+		assert.NoError(t, err)
+		input.AssertBufferConsumed(t)
+
+		assert.True(t, strings.HasPrefix(output.String(), "\x1b[34mfirst\r\n"+ansiClearToEnd+"second\x1b[0m $ "+ansiReset))
+		// the first prompt line is not redrawn
+		assert.Equal(t, 1, strings.Count(output.String(), "first"))
+	})
+}
+
+func TestReadCommandRightPrompt(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		output.Width = 20
+		input.PutString("foo\n")
+		_, err := ReadCommand("cle", &ReadCommandOptions{RightPrompt: "\x1b[33m[prod]\x1b[0m"})
+		assert.NoError(t, err)
+		input.AssertBufferConsumed(t)
+
+		// right prompt ends one column before the right border: 20 - 6 - 1 = 13
+		assert.True(t, strings.Contains(output.String(), "cle> foo"+cursorForward(5)+"\x1b[33m[prod]\x1b[0m"))
+
+		output.Reset()
+		input.PutString("foobarbaz\n")
+		_, err = ReadCommand("cle", &ReadCommandOptions{RightPrompt: "[prod]"})
+		assert.NoError(t, err)
+		input.AssertBufferConsumed(t)
+
+		// right prompt disappears as soon as the input reaches it
+		assert.True(t, strings.Contains(output.String(), "cle> foobarb"+cursorForward(1)+"[prod]"))
+		assert.False(t, strings.Contains(output.String(), "cle> foobarba"+cursorForward(1)))
+		assert.Equal(t, len("foobarb")+1, strings.Count(output.String(), "[prod]"))
+	})
+}
+
+func TestTruncateToWidth(t *testing.T) {
+	assert.Equal(t, "foobar", truncateToWidth("foobar", 6))
+	assert.Equal(t, "foob…", truncateToWidth("foobar", 5))
+	assert.Equal(t, "日…", truncateToWidth("日本語", 4))
+	assert.Equal(t, "\x1b[2mfo\x1b[0m…", truncateToWidth("\x1b[2mfoobar", 3))
+	assert.Equal(t, "", truncateToWidth("foobar", 0))
+}
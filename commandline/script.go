@@ -0,0 +1,220 @@
+package commandline
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// defaultScriptName is used in error messages for scripts without file name.
+const defaultScriptName = "script"
+
+// maxScriptDepth limits the nesting of sourced scripts, e.g. for cycles that are not detected by path.
+const maxScriptDepth = 64
+
+// ErrRecursiveScript is returned when a script is sourced while it is already being executed.
+type ErrRecursiveScript struct {
+	File string
+}
+
+func (e ErrRecursiveScript) Error() string {
+	return fmt.Sprintf("%s is sourced recursively", e.File)
+}
+
+// activeScriptsKey is the context key for the paths of the scripts that are currently executed by source commands.
+type activeScriptsKey struct{}
+
+// ErrScript is returned when a command of a script fails.
+type ErrScript struct {
+	// File denotes the name of the script file.
+	File string
+	// Line denotes the line number where the failed command starts, beginning at 1.
+	Line int
+	Err  error
+}
+
+func (e ErrScript) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Err.Error())
+}
+
+func (e ErrScript) Unwrap() error {
+	return e.Err
+}
+
+// ExecLine parses a command line with variable expansion and executes it. Commands can be connected by | and redirected to files by >, >> and <.
+// Pipelines can be joined by ;, && and || and are evaluated like in a shell based on the returned errors.
+func (b *Environment) ExecLine(line string) error {
+	_, err := b.execLine(context.Background(), line)
+	return err
+}
+
+// execLine executes a command line and returns the command that caused the error.
+func (b *Environment) execLine(ctx context.Context, line string) ([]string, error) {
+	return b.execLineStreams(ctx, line, ConsoleStreams())
+}
+
+// execLineStreams executes a command line like execLine with streams instead of the console streams.
+func (b *Environment) execLineStreams(ctx context.Context, line string, streams Streams) ([]string, error) {
+	l, err := parseCommandList(line, b.expandVars)
+	if err != nil {
+		cmd, _ := b.ParseLine(line)
+		return cmd, err
+	}
+	return b.execCommandList(ctx, l, streams)
+}
+
+// commandName returns the name of cmd or an empty string.
+func commandName(cmd []string) string {
+	if len(cmd) == 0 {
+		return ""
+	}
+	return cmd[0]
+}
+
+// commandArgs returns the arguments of cmd.
+func commandArgs(cmd []string) []string {
+	if len(cmd) == 0 {
+		return nil
+	}
+	return cmd[1:]
+}
+
+// RunScript executes all commands read from r line by line until an error is returned. Use ErrExit to gracefully stop processing.
+//
+// Lines starting with # and everything behind an unquoted # are ignored. Quotes may span multiple lines. Errors are of type ErrScript.
+// With ContinueScriptOnError, failed commands are passed to ErrorHandler and processing continues.
+func (b *Environment) RunScript(r io.Reader) error {
+	name := defaultScriptName
+	if named, ok := r.(interface{ Name() string }); ok {
+		name = named.Name()
+	}
+
+	b.onStart()
+	err := b.runScript(context.Background(), name, r)
+	if errors.Is(err, ErrExit) {
+		err = nil
+	}
+	b.onExit(err)
+	return err
+}
+
+// RunScriptFile executes the script stored in the given file like RunScript.
+func (b *Environment) RunScriptFile(path string) error {
+	b.onStart()
+	err := b.runScriptFile(context.Background(), path)
+	if errors.Is(err, ErrExit) {
+		err = nil
+	}
+	b.onExit(err)
+	return err
+}
+
+// runScriptFile executes the script stored in the given file like runScript. Scripts that are already executed in ctx are not executed again.
+func (b *Environment) runScriptFile(ctx context.Context, path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	// scripts are tracked per context, so background jobs may source the same script concurrently
+	active, _ := ctx.Value(activeScriptsKey{}).([]string)
+	if slices.Contains(active, absPath) {
+		return ErrRecursiveScript{File: path}
+	}
+	if len(active) >= maxScriptDepth {
+		return fmt.Errorf("scripts are nested more than %d levels deep", maxScriptDepth)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	ctx = context.WithValue(ctx, activeScriptsKey{}, append(slices.Clip(active), absPath))
+	return b.runScript(ctx, path, f)
+}
+
+// runScript executes a script and returns ErrExit if the script has been stopped. Cancellation of ctx stops the script before the next command.
+func (b *Environment) runScript(ctx context.Context, name string, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	startLine := 0
+	command := ""
+
+	for scanner.Scan() {
+		lineNumber++
+		if len(command) == 0 {
+			startLine = lineNumber
+			command = scanner.Text()
+		} else {
+			// line break is part of a quoted command part
+			command += "\n" + scanner.Text()
+		}
+
+		command = stripComment(command)
+		if _, isComplete := TokenizeCommand(command); !isComplete {
+			continue
+		}
+
+		line := command
+		command = ""
+		if err := ctx.Err(); err != nil {
+			return ErrScript{File: name, Line: startLine, Err: err}
+		}
+		b.beforeExec(line)
+		cmd, err := b.execLine(ctx, line)
+		b.afterExec(line, err)
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, ErrExit) {
+			return err
+		}
+
+		err = ErrScript{File: name, Line: startLine, Err: err}
+		if !b.ContinueScriptOnError || b.ErrorHandler == nil {
+			return err
+		}
+		b.ErrorHandler(commandName(cmd), commandArgs(cmd), err)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if len(command) > 0 {
+		return ErrScript{File: name, Line: startLine, Err: fmt.Errorf("unterminated quote or escape sequence")}
+	}
+	return nil
+}
+
+// stripComment removes everything behind the first unquoted command part starting with #.
+func stripComment(command string) string {
+	tokens, _ := TokenizeCommand(command)
+	for _, t := range tokens {
+		if len(t.Segments) > 0 && t.Segments[0].Kind == SegmentPlain && len(t.Segments[0].Value) > 0 && t.Segments[0].Value[0] == '#' {
+			return command[:t.Start]
+		}
+	}
+	return command
+}
+
+// NewSourceCommand returns a named command that executes a script file like RunScript.
+func NewSourceCommand(name string, env *Environment) Command {
+	return Describe(NewContextCommand(name,
+		NewFixedArgCompletion(NewLocalFileSystemArgCompletion(true)),
+		func(ctx context.Context, args []string) error {
+			if len(args) != 1 {
+				return ErrInvalidUsage{Err: fmt.Errorf("expected exactly one file"), Usage: fmt.Sprintf("Usage: %s <file>\n", name)}
+			}
+
+			return env.runScriptFile(ctx, args[0])
+		}), CommandInfo{
+		Description: "execute commands from a file",
+		Usage:       fmt.Sprintf("Usage: %s <file>\n", name),
+	})
+}
@@ -0,0 +1,113 @@
+package commandline
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DENICeG/go-console/v2/consoletest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func prepareScriptCLE() (*Environment, *strings.Builder) {
+	cle, _, sb := prepareTestCLE()
+	cle.RegisterCommand(NewSetCommand("set", cle))
+	cle.RegisterCommand(NewSourceCommand("source", cle))
+	cle.RegisterCommand(NewCustomCommand("fail", nil, func(args []string) error {
+		return fmt.Errorf("failed with %s", strings.Join(args, ","))
+	}))
+	return cle, sb
+}
+
+func TestRunScript(t *testing.T) {
+	cle, sb := prepareScriptCLE()
+
+	script := `# maintenance procedure
+set ZONE example.com
+
+print $ZONE # trailing comment
+print "multi
+line" '# no comment' \#escaped foo#bar
+   # indented comment
+exit
+print unreachable
+`
+	require.NoError(t, cle.RunScript(strings.NewReader(script)))
+	assert.Equal(t, ">example.com<|>multi\nline<># no comment<>#escaped<>foo#bar<|", sb.String())
+}
+
+func TestRunScriptErrors(t *testing.T) {
+	cle, sb := prepareScriptCLE()
+
+	script := "print a\n\nfail \"x\ny\"\nprint b\nfail z\n"
+	err := cle.RunScript(strings.NewReader(script))
+	assert.EqualError(t, err, "script:3: failed with x\ny")
+	var errScript ErrScript
+	require.ErrorAs(t, err, &errScript)
+	assert.Equal(t, 3, errScript.Line)
+	assert.Equal(t, ">a<|", sb.String())
+
+	err = cle.RunScript(strings.NewReader("print a\nprint \"b\n"))
+	assert.EqualError(t, err, "script:2: unterminated quote or escape sequence")
+}
+
+func TestRunScriptContinueOnError(t *testing.T) {
+	cle, sb := prepareScriptCLE()
+	cle.ContinueScriptOnError = true
+	errors := make([]string, 0)
+	cle.ErrorHandler = func(cmd string, _ []string, err error) error {
+		errors = append(errors, cmd+": "+err.Error())
+		return nil
+	}
+
+	require.NoError(t, cle.RunScript(strings.NewReader("fail a\nprint b\nfail c\n")))
+	assert.Equal(t, ">b<|", sb.String())
+	assert.Equal(t, []string{"fail: script:1: failed with a", "fail: script:3: failed with c"}, errors)
+}
+
+func TestSourceCommand(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		dir := t.TempDir()
+		inner := filepath.Join(dir, "inner.cle")
+		outer := filepath.Join(dir, "outer.cle")
+		require.NoError(t, os.WriteFile(inner, []byte("print inner\nfail inner\n"), 0o600))
+		require.NoError(t, os.WriteFile(outer, []byte(fmt.Sprintf("print outer\nsource %s\n", Quote(inner))), 0o600))
+
+		cle, sb := prepareScriptCLE()
+		err := cle.RunScriptFile(outer)
+		assert.EqualError(t, err, fmt.Sprintf("%s:2: %s:2: failed with inner", outer, inner))
+		assert.Equal(t, ">outer<|>inner<|", sb.String())
+
+		// exit in a sourced script stops the interactive session
+		require.NoError(t, os.WriteFile(inner, []byte("exit\n"), 0o600))
+		input.PutString(fmt.Sprintf("source %s\n", Quote(inner)))
+		assert.NoError(t, cle.Run())
+		input.AssertBufferConsumed(t)
+	})
+}
+
+func TestSourceCommandRecursion(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.cle")
+	b := filepath.Join(dir, "b.cle")
+	require.NoError(t, os.WriteFile(a, []byte(fmt.Sprintf("print a\nsource %s\n", Quote(b))), 0o600))
+	require.NoError(t, os.WriteFile(b, []byte(fmt.Sprintf("print b\nsource %s\n", Quote(a))), 0o600))
+
+	cle, sb := prepareScriptCLE()
+	err := cle.RunScriptFile(a)
+	var errScript ErrScript
+	require.ErrorAs(t, err, &errScript)
+	assert.ErrorIs(t, err, ErrRecursiveScript{File: a})
+	assert.EqualError(t, err, fmt.Sprintf("%s:2: %s:2: %s is sourced recursively", a, b, a))
+	assert.Equal(t, ">a<|>b<|", sb.String())
+
+	// the same script can be sourced again after it has finished
+	sb.Reset()
+	require.NoError(t, os.WriteFile(b, []byte("print b\n"), 0o600))
+	require.NoError(t, cle.ExecLine(fmt.Sprintf("source %s; source %s", Quote(b), Quote(b))))
+	assert.Equal(t, ">b<|>b<|", sb.String())
+}
@@ -0,0 +1,176 @@
+package commandline
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/DENICeG/go-console/v2"
+)
+
+// Streams contains the standard streams of a command.
+type Streams struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// ConsoleStreams returns streams that read from the console input and write to the console output.
+func ConsoleStreams() Streams {
+	out := &outputWriter{out: console.DefaultOutput}
+	return Streams{Stdin: &inputReader{}, Stdout: out, Stderr: out}
+}
+
+// StreamCommand is implemented by commands that read from and write to streams, so they can be used in pipelines and with redirections like list | grep pending > out.txt.
+type StreamCommand interface {
+	Command
+	// ExecStreams is called instead of Exec to execute the command with a set of arguments and the given streams.
+	ExecStreams(args []string, streams Streams) error
+}
+
+// ExecStreamCommandHandler is called when processing a stream command. Return ErrExit to gracefully stop processing.
+type ExecStreamCommandHandler func(args []string, streams Streams) error
+
+type streamCommand struct {
+	completionHandler CommandCompletionHandler
+	execHandler       ExecStreamCommandHandler
+	name              string
+}
+
+// NewStreamCommand returns a named command with completion handler that reads from and writes to streams. Exec uses ConsoleStreams.
+func NewStreamCommand(name string, completionHandler CommandCompletionHandler, execHandler ExecStreamCommandHandler) Command {
+	return &streamCommand{
+		name:              name,
+		completionHandler: completionHandler,
+		execHandler:       execHandler,
+	}
+}
+
+func (c *streamCommand) Name() string {
+	return c.name
+}
+
+func (c *streamCommand) GetCompletionOptions(currentCommand []string, entryIndex int) []CompletionOption {
+	if c.completionHandler != nil {
+		return c.completionHandler(currentCommand, entryIndex)
+	}
+	return nil
+}
+
+func (c *streamCommand) Exec(args []string) error {
+	return c.ExecStreams(args, ConsoleStreams())
+}
+
+func (c *streamCommand) ExecStreams(args []string, streams Streams) error {
+	if c.execHandler != nil {
+		return c.execHandler(args, streams)
+	}
+	return nil
+}
+
+// asStreamCommand returns cmd as StreamCommand if it supports streams. Commands wrapped by Describe are unwrapped.
+func asStreamCommand(cmd Command) (StreamCommand, bool) {
+	s, ok := unwrapCommand(cmd).(StreamCommand)
+	return s, ok
+}
+
+// asContextCommand returns cmd as ContextCommand if it supports cancellation. Commands wrapped by Describe are unwrapped.
+func asContextCommand(cmd Command) (ContextCommand, bool) {
+	c, ok := unwrapCommand(cmd).(ContextCommand)
+	return c, ok
+}
+
+// leafCommand returns the command that is executed for args, which is a child command for command groups. Commands wrapped by Describe are unwrapped.
+//
+// Command groups implement StreamCommand and ContextCommand, so the capabilities need to be checked on the returned command.
+func leafCommand(cmd Command, args []string) Command {
+	for {
+		cmd = unwrapCommand(cmd)
+		g, ok := cmd.(*commandGroup)
+		if !ok || len(args) == 0 {
+			return cmd
+		}
+		child, exists := g.children[args[0]]
+		if !exists {
+			return cmd
+		}
+		cmd, args = child, args[1:]
+	}
+}
+
+// unwrapCommand returns the command wrapped by Describe.
+func unwrapCommand(cmd Command) Command {
+	for {
+		d, ok := cmd.(*describedCommand)
+		if !ok {
+			return cmd
+		}
+		cmd = d.Command
+	}
+}
+
+// outputMutex serializes writes of all outputWriters, e.g. of concurrent background jobs.
+var outputMutex sync.Mutex
+
+// outputWriter writes to a console output. Concurrent writes are serialized.
+type outputWriter struct {
+	out console.Output
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	outputMutex.Lock()
+	defer outputMutex.Unlock()
+	return w.out.Print(string(p))
+}
+
+// inputReader reads lines from the console input.
+type inputReader struct {
+	buffer []byte
+	err    error
+}
+
+func (r *inputReader) Read(p []byte) (int, error) {
+	for len(r.buffer) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		line, err := console.ReadLine()
+		if err == nil {
+			line += "\n"
+		}
+		r.buffer = []byte(line)
+		r.err = err
+	}
+
+	n := copy(p, r.buffer)
+	r.buffer = r.buffer[n:]
+	return n, nil
+}
+
+// writerOutput is a console output that writes to w without escape sequences.
+type writerOutput struct {
+	w      io.Writer
+	parent console.Output
+}
+
+func (o *writerOutput) Print(str string) (int, error) {
+	return io.WriteString(o.w, str)
+}
+
+func (o *writerOutput) GetSize() (int, int, error) {
+	return o.parent.GetSize()
+}
+
+func (o *writerOutput) SupportsColors() bool {
+	// redirected output should not contain escape sequences
+	return false
+}
+
+func (o *writerOutput) Exit(code int) {
+	o.parent.Exit(code)
+}
+
+// isBrokenPipe returns true if err has been caused by a pipeline reader that stopped reading early, like head.
+func isBrokenPipe(err error) bool {
+	return errors.Is(err, io.ErrClosedPipe)
+}
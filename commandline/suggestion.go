@@ -0,0 +1,83 @@
+package commandline
+
+import (
+	"strings"
+)
+
+// SuggestionHandler returns a suggestion to complete the entered command.
+//
+// The returned string is displayed behind the cursor and must not repeat the entered command. An empty string denotes that there is no suggestion.
+type SuggestionHandler func(command string) string
+
+// NewHistorySuggestion returns a suggestion handler that suggests the most recent matching command from history.
+func NewHistorySuggestion(getHistoryEntry CommandHistoryHandler) SuggestionHandler {
+	return NewLineHistorySuggestion(func(index int) (string, bool) {
+		entry, ok := getHistoryEntry(index)
+		return GetCommandString(entry), ok
+	})
+}
+
+// NewLineHistorySuggestion returns a suggestion handler like NewHistorySuggestion for command lines that are stored as entered.
+func NewLineHistorySuggestion(getHistoryLine LineHistoryHandler) SuggestionHandler {
+	return func(command string) string {
+		if len(command) == 0 {
+			return ""
+		}
+
+		for i := 0; ; i++ {
+			line, ok := getHistoryLine(i)
+			if !ok {
+				return ""
+			}
+
+			if len(line) > len(command) && strings.HasPrefix(line, command) {
+				return line[len(command):]
+			}
+		}
+	}
+}
+
+// NewCompletionSuggestion returns a suggestion handler that suggests the remainder of the current command part if there is exactly one completion option.
+func NewCompletionSuggestion(getCompletionOptions CommandCompletionHandler) SuggestionHandler {
+	return func(command string) string {
+		if len(command) == 0 {
+			return ""
+		}
+
+		cmd, _ := ParseCommand(command)
+		if len(cmd) == 0 || strings.HasSuffix(command, " ") {
+			cmd = append(cmd, "")
+		}
+
+		prefix := cmd[len(cmd)-1]
+		options := matchOptions(getCompletionOptions(cmd, len(cmd)-1), prefix, PrefixMatcher)
+		if len(options) != 1 || len(options[0].Replacement()) <= len(prefix) {
+			return ""
+		}
+		return Escape(options[0].Replacement()[len(prefix):])
+	}
+}
+
+// CombineSuggestions returns a suggestion handler that returns the first suggestion of the given handlers.
+func CombineSuggestions(handlers ...SuggestionHandler) SuggestionHandler {
+	return func(command string) string {
+		for _, h := range handlers {
+			if suggestion := h(command); len(suggestion) > 0 {
+				return suggestion
+			}
+		}
+		return ""
+	}
+}
+
+// nextSuggestedWord returns the first word of suggestion including leading whitespaces.
+func nextSuggestedWord(suggestion string) string {
+	i := 0
+	for i < len(suggestion) && suggestion[i] == ' ' {
+		i++
+	}
+	if end := strings.IndexByte(suggestion[i:], ' '); end >= 0 {
+		return suggestion[:i+end]
+	}
+	return suggestion
+}
@@ -0,0 +1,63 @@
+package commandline
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DENICeG/go-console/v2"
+	"github.com/DENICeG/go-console/v2/consoletest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistorySuggestion(t *testing.T) {
+	hist := NewCommandHistory(10)
+	hist.Put([]string{"info", "example.de"})
+	hist.Put([]string{"info", "white space"})
+	hist.Put([]string{"exit"})
+
+	suggest := NewHistorySuggestion(hist.GetHistoryEntry)
+	assert.Equal(t, "", suggest(""))
+	assert.Equal(t, "nfo \"white space\"", suggest("i"))
+	assert.Equal(t, "xample.de", suggest("info e"))
+	assert.Equal(t, "", suggest("exit"))
+	assert.Equal(t, "", suggest("foo"))
+}
+
+func TestCompletionSuggestion(t *testing.T) {
+	suggest := NewCompletionSuggestion(func(cmd []string, index int) []CompletionOption {
+		if index == 0 {
+			return PrepareCompletionOptions([]string{"print", "exit"}, false)
+		}
+		return PrepareCompletionOptions([]string{"foo bar", "baz"}, false)
+	})
+	assert.Equal(t, "", suggest(""))
+	assert.Equal(t, "rint", suggest("p"))
+	assert.Equal(t, "", suggest("print "))
+	assert.Equal(t, "oo\\ bar", suggest("print f"))
+}
+
+func TestNextSuggestedWord(t *testing.T) {
+	assert.Equal(t, "foo", nextSuggestedWord("foo bar"))
+	assert.Equal(t, " bar", nextSuggestedWord(" bar baz"))
+	assert.Equal(t, "bar", nextSuggestedWord("bar"))
+}
+
+func TestCommandLineEnvironmentSuggestion(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		input.PutString("print foo bar\n")
+		input.PutString("pr")
+		input.PutKeys(console.KeyRight, console.KeyEnter)
+		input.PutString("p")
+		input.PutKeyWithRune(console.KeyAlt, 'f')
+		input.PutKeyWithRune(console.KeyAlt, 'f')
+		input.PutString("\nexit\n")
+
+		cle, _, sb := prepareTestCLE()
+
+		assert.NoError(t, cle.Run())
+		assert.Equal(t, ">foo<>bar<|>foo<>bar<|>foo<|", sb.String())
+		assert.True(t, strings.Contains(output.String(), styleSuggestion+"int foo bar"+ansiReset))
+		input.AssertBufferConsumed(t)
+	})
+}
@@ -0,0 +1,144 @@
+package commandline
+
+import (
+	"strings"
+)
+
+// SegmentKind denotes how a part of a token has been written in the command line.
+type SegmentKind int
+
+const (
+	// SegmentPlain denotes unquoted text.
+	SegmentPlain SegmentKind = iota
+	// SegmentEscaped denotes an escape sequence like \" or \$.
+	SegmentEscaped
+	// SegmentSingleQuoted denotes text in single quotes.
+	SegmentSingleQuoted
+	// SegmentDoubleQuoted denotes text in double quotes.
+	SegmentDoubleQuoted
+)
+
+// TokenSegment denotes a consecutive part of a token that has been written with the same quoting.
+type TokenSegment struct {
+	// Start and End denote the byte offsets in the command line including quotes and escape characters.
+	Start, End int
+	// Value is the unescaped value of the segment.
+	Value string
+	Kind  SegmentKind
+}
+
+// Token denotes a single command part with its position in the command line.
+type Token struct {
+	// Value is the unescaped command part as returned by ParseCommand.
+	Value string
+	// Start and End denote the byte offsets in the command line including quotes and escape characters.
+	Start, End int
+	Segments   []TokenSegment
+}
+
+// TokenizeCommand splits a command line like ParseCommand, but also returns the position and quoting of every token.
+//
+// Tokens with an empty value, like a sole pair of quotes, are also returned. The return parameter isComplete is false when a quote or escape sequence is not closed.
+func TokenizeCommand(str string) (tokens []Token, isComplete bool) {
+	tokens = make([]Token, 0)
+
+	var current *Token
+	var segment *TokenSegment
+	var sb strings.Builder
+
+	// finishSegment closes the current segment at the given offset
+	finishSegment := func(end int) {
+		if segment != nil {
+			segment.End = end
+			segment.Value = sb.String()
+			current.Segments = append(current.Segments, *segment)
+			segment = nil
+			sb.Reset()
+		}
+	}
+	// startSegment closes the current segment and opens a new one at the given offset
+	startSegment := func(start int, kind SegmentKind) {
+		finishSegment(start)
+		if current == nil {
+			current = &Token{Start: start, Segments: make([]TokenSegment, 0)}
+		}
+		segment = &TokenSegment{Start: start, Kind: kind}
+	}
+	finishToken := func(end int) {
+		finishSegment(end)
+		if current != nil {
+			current.End = end
+			var value strings.Builder
+			for _, s := range current.Segments {
+				value.WriteString(s.Value)
+			}
+			current.Value = value.String()
+			tokens = append(tokens, *current)
+			current = nil
+		}
+	}
+
+	escape := false
+	doubleQuote := false
+	singleQuote := false
+
+	for i, r := range str {
+		switch {
+		case singleQuote:
+			if r == '\'' {
+				singleQuote = false
+				finishSegment(i + 1)
+			} else {
+				sb.WriteRune(r)
+			}
+		case doubleQuote:
+			if escape {
+				if r != '\\' && r != '$' && r != '"' {
+					// consume escape character only for actual escape sequences
+					sb.WriteRune('\\')
+				}
+				sb.WriteRune(r)
+				escape = false
+				// continue with the remainder of the quoted string
+				finishSegment(i + len(string(r)))
+				startSegment(i+len(string(r)), SegmentDoubleQuoted)
+			} else {
+				if r == '"' {
+					doubleQuote = false
+					finishSegment(i + 1)
+				} else if r == '\\' {
+					escape = true
+					startSegment(i, SegmentEscaped)
+				} else {
+					sb.WriteRune(r)
+				}
+			}
+		case escape:
+			sb.WriteRune(r)
+			escape = false
+			finishSegment(i + len(string(r)))
+		default:
+			if r == '\\' {
+				escape = true
+				startSegment(i, SegmentEscaped)
+			} else if r == '\'' {
+				singleQuote = true
+				startSegment(i, SegmentSingleQuoted)
+			} else if r == '"' {
+				doubleQuote = true
+				startSegment(i, SegmentDoubleQuoted)
+			} else if r == ' ' {
+				finishToken(i)
+			} else {
+				if segment == nil || segment.Kind != SegmentPlain {
+					startSegment(i, SegmentPlain)
+				}
+				sb.WriteRune(r)
+			}
+		}
+	}
+
+	finishToken(len(str))
+
+	return tokens, (!escape && !singleQuote && !doubleQuote)
+}
@@ -0,0 +1,46 @@
+package commandline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenizeCommand(t *testing.T) {
+	tokens, isComplete := TokenizeCommand(`echo  'a b' x"y\$"z\ `)
+	assert.True(t, isComplete)
+	assert.Len(t, tokens, 3)
+
+	assert.Equal(t, Token{Value: "echo", Start: 0, End: 4, Segments: []TokenSegment{
+		{Start: 0, End: 4, Value: "echo", Kind: SegmentPlain},
+	}}, tokens[0])
+	assert.Equal(t, Token{Value: "a b", Start: 6, End: 11, Segments: []TokenSegment{
+		{Start: 6, End: 11, Value: "a b", Kind: SegmentSingleQuoted},
+	}}, tokens[1])
+	assert.Equal(t, Token{Value: "xy$z ", Start: 12, End: 21, Segments: []TokenSegment{
+		{Start: 12, End: 13, Value: "x", Kind: SegmentPlain},
+		{Start: 13, End: 15, Value: "y", Kind: SegmentDoubleQuoted},
+		{Start: 15, End: 17, Value: "$", Kind: SegmentEscaped},
+		{Start: 17, End: 18, Value: "", Kind: SegmentDoubleQuoted},
+		{Start: 18, End: 19, Value: "z", Kind: SegmentPlain},
+		{Start: 19, End: 21, Value: " ", Kind: SegmentEscaped},
+	}}, tokens[2])
+}
+
+func TestTokenizeIncompleteCommand(t *testing.T) {
+	tokens, isComplete := TokenizeCommand(`foo "bar`)
+	assert.False(t, isComplete)
+	assert.Len(t, tokens, 2)
+	assert.Equal(t, "bar", tokens[1].Value)
+	assert.Equal(t, 4, tokens[1].Start)
+	assert.Equal(t, 8, tokens[1].End)
+}
+
+func TestTokenizeEmptyQuotes(t *testing.T) {
+	tokens, _ := TokenizeCommand(`foo "" bar`)
+	assert.Len(t, tokens, 3)
+	assert.Equal(t, "", tokens[1].Value)
+
+	cmd, _ := ParseCommand(`foo "" bar`)
+	assert.Equal(t, []string{"foo", "bar"}, cmd)
+}
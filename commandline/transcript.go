@@ -0,0 +1,378 @@
+package commandline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DENICeG/go-console/v2"
+)
+
+// Types of transcript events.
+const (
+	// TranscriptStart is the first event and contains the terminal size.
+	TranscriptStart = "start"
+	// TranscriptPrompt contains the prompt that is displayed before a command line is read.
+	TranscriptPrompt = "prompt"
+	// TranscriptCommand contains a command line that is about to be executed.
+	TranscriptCommand = "command"
+	// TranscriptResult follows the output of a command line and contains its duration and error.
+	TranscriptResult = "result"
+	// TranscriptOutput contains a chunk of printed output.
+	TranscriptOutput = "output"
+	// TranscriptInput contains a line read from the console input, e.g. to confirm an action.
+	TranscriptInput = "input"
+	// TranscriptPassword denotes a password read from the console input. The password itself is not recorded.
+	TranscriptPassword = "password"
+	// TranscriptEnd is the last event of a transcript.
+	TranscriptEnd = "end"
+)
+
+// TranscriptEvent denotes a single line of a JSONL session transcript.
+type TranscriptEvent struct {
+	// Time denotes the seconds since the start of the recording.
+	Time float64 `json:"time"`
+	Type string  `json:"type"`
+	// Data contains the prompt, command line, output or input depending on the type.
+	Data string `json:"data,omitempty"`
+	// Error contains the error message of a result.
+	Error string `json:"error,omitempty"`
+	// Duration denotes the execution time of a result in seconds.
+	Duration float64 `json:"duration,omitempty"`
+	// Width, Height and CommandsOnly describe the recording in the start event.
+	Width        int  `json:"width,omitempty"`
+	Height       int  `json:"height,omitempty"`
+	CommandsOnly bool `json:"commandsOnly,omitempty"`
+}
+
+// TranscriptRecorder writes a JSONL transcript of a console session with one TranscriptEvent per line.
+//
+// Attach records prompts, command lines, errors and timings of an Environment. Output and input are recorded by the wrappers returned by WrapOutput and WrapInput:
+//
+//	recorder := commandline.NewTranscriptRecorder(f, false)
+//	console.DefaultOutput = recorder.WrapOutput(console.DefaultOutput)
+//	console.DefaultInput = recorder.WrapInput(console.DefaultInput)
+//	recorder.Attach(env)
+//	err := env.Run()
+//	recorder.Close()
+type TranscriptRecorder struct {
+	mutex        sync.Mutex
+	encoder      *json.Encoder
+	commandsOnly bool
+	now          func() time.Time
+	start        time.Time
+	// execStarts contains the start times of running command lines, which are nested for scripts executed by source.
+	execStarts []time.Time
+	err        error
+}
+
+// NewTranscriptRecorder returns a recorder that writes to w. The start event is written immediately.
+//
+// With commandsOnly, only command lines and their results are recorded. Prompts, output and input are left out.
+func NewTranscriptRecorder(w io.Writer, commandsOnly bool) *TranscriptRecorder {
+	return newTranscriptRecorder(w, commandsOnly, time.Now)
+}
+
+func newTranscriptRecorder(w io.Writer, commandsOnly bool, now func() time.Time) *TranscriptRecorder {
+	encoder := json.NewEncoder(w)
+	// keep operators like && and > readable
+	encoder.SetEscapeHTML(false)
+
+	r := &TranscriptRecorder{encoder: encoder, commandsOnly: commandsOnly, now: now, start: now()}
+	width, height, _ := console.GetSize()
+	r.write(TranscriptEvent{Type: TranscriptStart, Width: max(width, 0), Height: max(height, 0), CommandsOnly: commandsOnly})
+	return r
+}
+
+// write adds e to the transcript. The first write error is kept for Close.
+func (r *TranscriptRecorder) write(e TranscriptEvent) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.writeLocked(e)
+}
+
+func (r *TranscriptRecorder) writeLocked(e TranscriptEvent) {
+	e.Time = seconds(r.now().Sub(r.start))
+	if err := r.encoder.Encode(e); err != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+// seconds returns d in seconds rounded to microseconds.
+func seconds(d time.Duration) float64 {
+	return math.Round(d.Seconds()*1e6) / 1e6
+}
+
+// Attach records prompts, command lines and their results of env. Existing hooks of env are still called.
+func (r *TranscriptRecorder) Attach(env *Environment) {
+	beforePrompt, beforeExec, afterExec := env.BeforePrompt, env.BeforeExec, env.AfterExec
+
+	env.BeforePrompt = func() {
+		if beforePrompt != nil {
+			beforePrompt()
+		}
+		if !r.commandsOnly {
+			r.write(TranscriptEvent{Type: TranscriptPrompt, Data: env.prompt()})
+		}
+	}
+
+	env.BeforeExec = func(line string) {
+		if beforeExec != nil {
+			beforeExec(line)
+		}
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		r.execStarts = append(r.execStarts, r.now())
+		r.writeLocked(TranscriptEvent{Type: TranscriptCommand, Data: line})
+	}
+
+	env.AfterExec = func(line string, err error) {
+		if afterExec != nil {
+			afterExec(line, err)
+		}
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		e := TranscriptEvent{Type: TranscriptResult, Error: errorMessage(err)}
+		if n := len(r.execStarts); n > 0 {
+			e.Duration = seconds(r.now().Sub(r.execStarts[n-1]))
+			r.execStarts = r.execStarts[:n-1]
+		}
+		r.writeLocked(e)
+	}
+}
+
+// errorMessage returns the message of err or an empty string for nil and ErrExit.
+func errorMessage(err error) string {
+	if err == nil || errors.Is(err, ErrExit) {
+		return ""
+	}
+	return err.Error()
+}
+
+// WrapOutput returns an output that records everything printed to out.
+func (r *TranscriptRecorder) WrapOutput(out console.Output) console.Output {
+	return &transcriptOutput{Output: out, recorder: r}
+}
+
+// WrapInput returns an input that records lines and passwords read from in. Keys are not recorded, because command lines are recorded by Attach.
+func (r *TranscriptRecorder) WrapInput(in console.Input) console.Input {
+	return &transcriptInput{Input: in, recorder: r}
+}
+
+// Close writes the end event and returns the first error that occurred while writing the transcript.
+func (r *TranscriptRecorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.writeLocked(TranscriptEvent{Type: TranscriptEnd})
+	return r.err
+}
+
+type transcriptOutput struct {
+	console.Output
+	recorder *TranscriptRecorder
+}
+
+func (o *transcriptOutput) Print(str string) (int, error) {
+	if !o.recorder.commandsOnly {
+		o.recorder.write(TranscriptEvent{Type: TranscriptOutput, Data: str})
+	}
+	return o.Output.Print(str)
+}
+
+func (o *transcriptOutput) Unwrap() console.Output {
+	return o.Output
+}
+
+type transcriptInput struct {
+	console.Input
+	recorder *TranscriptRecorder
+}
+
+func (i *transcriptInput) ReadLine() (string, error) {
+	line, err := i.Input.ReadLine()
+	if err == nil && !i.recorder.commandsOnly {
+		i.recorder.write(TranscriptEvent{Type: TranscriptInput, Data: line})
+	}
+	return line, err
+}
+
+func (i *transcriptInput) ReadPassword() (string, error) {
+	password, err := i.Input.ReadPassword()
+	if err == nil && !i.recorder.commandsOnly {
+		i.recorder.write(TranscriptEvent{Type: TranscriptPassword})
+	}
+	return password, err
+}
+
+func (i *transcriptInput) IsInteractive() bool {
+	if in, ok := i.Input.(console.InteractiveInput); ok {
+		return in.IsInteractive()
+	}
+	return true
+}
+
+// ReplayDiff denotes a recorded command line whose output or error differs when replayed.
+type ReplayDiff struct {
+	Line           string
+	ExpectedOutput string
+	ActualOutput   string
+	ExpectedError  string
+	ActualError    string
+}
+
+// String returns the command line followed by the differing error and a line diff of the output. Removed lines are prefixed by -, added lines by +.
+func (d ReplayDiff) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "$ %s\n", d.Line)
+	if d.ExpectedError != d.ActualError {
+		fmt.Fprintf(&sb, "-ERROR: %s\n+ERROR: %s\n", d.ExpectedError, d.ActualError)
+	}
+	if d.ExpectedOutput != d.ActualOutput {
+		sb.WriteString(diffLines(d.ExpectedOutput, d.ActualOutput))
+	}
+	return sb.String()
+}
+
+// recordedCommand denotes a command line of a transcript with its output and error.
+type recordedCommand struct {
+	line   string
+	output strings.Builder
+	err    string
+}
+
+// ReplayTranscript executes the command lines of a transcript read from r with env and returns all commands whose output or error differs from the recording.
+//
+// Output is not compared for transcripts recorded with commandsOnly. Command lines of scripts executed by source are not replayed separately. Replay stops at ErrExit.
+func ReplayTranscript(env *Environment, r io.Reader) ([]ReplayDiff, error) {
+	commands, commandsOnly, err := readTranscript(r)
+	if err != nil {
+		return nil, err
+	}
+
+	diffs := make([]ReplayDiff, 0)
+	for _, c := range commands {
+		var sb strings.Builder
+		previous := console.DefaultOutput
+		console.DefaultOutput = &captureOutput{writerOutput{w: &sb, parent: previous}}
+		err := env.ExecLine(c.line)
+		console.DefaultOutput = previous
+
+		d := ReplayDiff{
+			Line:           c.line,
+			ExpectedOutput: c.output.String(),
+			ActualOutput:   sb.String(),
+			ExpectedError:  c.err,
+			ActualError:    errorMessage(err),
+		}
+		if commandsOnly {
+			d.ExpectedOutput, d.ActualOutput = "", ""
+		}
+		if d.ExpectedOutput != d.ActualOutput || d.ExpectedError != d.ActualError {
+			diffs = append(diffs, d)
+		}
+
+		if errors.Is(err, ErrExit) {
+			break
+		}
+	}
+	return diffs, nil
+}
+
+// readTranscript returns the top-level command lines of a transcript.
+func readTranscript(r io.Reader) ([]*recordedCommand, bool, error) {
+	commands := make([]*recordedCommand, 0)
+	commandsOnly := false
+	// depth denotes the nesting of command lines, which is greater than 1 for scripts executed by source
+	depth := 0
+
+	decoder := json.NewDecoder(r)
+	for line := 1; ; line++ {
+		var e TranscriptEvent
+		if err := decoder.Decode(&e); errors.Is(err, io.EOF) {
+			return commands, commandsOnly, nil
+		} else if err != nil {
+			return nil, false, fmt.Errorf("invalid transcript event %d: %w", line, err)
+		}
+
+		switch e.Type {
+		case TranscriptStart:
+			commandsOnly = e.CommandsOnly
+		case TranscriptCommand:
+			if depth == 0 {
+				commands = append(commands, &recordedCommand{line: e.Data})
+			}
+			depth++
+		case TranscriptOutput:
+			if depth > 0 {
+				commands[len(commands)-1].output.WriteString(e.Data)
+			}
+		case TranscriptResult:
+			if depth == 1 {
+				commands[len(commands)-1].err = e.Error
+			}
+			depth = max(depth-1, 0)
+		}
+	}
+}
+
+// captureOutput is used to capture output during replay. Unlike writerOutput, colors are supported like by the parent output.
+type captureOutput struct {
+	writerOutput
+}
+
+func (o *captureOutput) SupportsColors() bool {
+	return o.parent.SupportsColors()
+}
+
+// diffLines returns a line diff between expected and actual. Unchanged lines are prefixed by a space.
+func diffLines(expected, actual string) string {
+	a := strings.SplitAfter(expected, "\n")
+	b := strings.SplitAfter(actual, "\n")
+
+	// lcs[i][k] denotes the length of the longest common subsequence of a[i:] and b[k:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for k := len(b) - 1; k >= 0; k-- {
+			if a[i] == b[k] {
+				lcs[i][k] = lcs[i+1][k+1] + 1
+			} else {
+				lcs[i][k] = max(lcs[i+1][k], lcs[i][k+1])
+			}
+		}
+	}
+
+	var sb strings.Builder
+	writeLine := func(prefix, line string) {
+		if len(line) == 0 {
+			// empty remainder behind the last line break
+			return
+		}
+		sb.WriteString(prefix)
+		sb.WriteString(strings.TrimSuffix(line, "\n"))
+		sb.WriteString("\n")
+	}
+	i, k := 0, 0
+	for i < len(a) || k < len(b) {
+		switch {
+		case i < len(a) && k < len(b) && a[i] == b[k]:
+			writeLine(" ", a[i])
+			i++
+			k++
+		case i < len(a) && (k == len(b) || lcs[i+1][k] >= lcs[i][k+1]):
+			writeLine("-", a[i])
+			i++
+		default:
+			writeLine("+", b[k])
+			k++
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,176 @@
+package commandline
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DENICeG/go-console/v2"
+	"github.com/DENICeG/go-console/v2/consoletest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// prepareTranscriptCLE returns an environment with an echo command that uses transform for its output.
+func prepareTranscriptCLE(transform func(string) string) *Environment {
+	cle := NewEnvironment()
+	cle.SetStaticPrompt("cle")
+	cle.RegisterCommand(NewExitCommand("exit"))
+	cle.RegisterCommand(NewCustomCommand("echo", nil, func(args []string) error {
+		_, err := console.Println(transform(strings.Join(args, " ")))
+		return err
+	}))
+	cle.RegisterCommand(NewCustomCommand("fail", nil, func(args []string) error {
+		return fmt.Errorf("failed with %s", strings.Join(args, ","))
+	}))
+	return cle
+}
+
+// recordSession runs the given input non-interactively and returns the transcript.
+func recordSession(t *testing.T, input string, commandsOnly bool) string {
+	var buffer bytes.Buffer
+	consoletest.WithOutputMocks(func(in *consoletest.MockInput, out *consoletest.MockOutput) {
+		in.NonInteractive = true
+		in.PutString(input)
+
+		clock := time.Unix(0, 0)
+		recorder := newTranscriptRecorder(&buffer, commandsOnly, func() time.Time {
+			clock = clock.Add(time.Second)
+			return clock
+		})
+		console.DefaultOutput = recorder.WrapOutput(out)
+		console.DefaultInput = recorder.WrapInput(in)
+
+		cle := prepareTranscriptCLE(func(s string) string { return s })
+		recorder.Attach(cle)
+		require.NoError(t, cle.Run())
+		require.NoError(t, recorder.Close())
+	})
+	return buffer.String()
+}
+
+// decodeTranscript returns the events of a transcript. Times are checked to be increasing and removed.
+func decodeTranscript(t *testing.T, transcript string) []TranscriptEvent {
+	events := make([]TranscriptEvent, 0)
+	last := 0.0
+	for _, line := range strings.Split(strings.TrimSuffix(transcript, "\n"), "\n") {
+		var e TranscriptEvent
+		require.NoError(t, json.Unmarshal([]byte(line), &e), line)
+		assert.Greater(t, e.Time, last)
+		last = e.Time
+		if e.Type == TranscriptResult {
+			assert.Greater(t, e.Duration, 0.0)
+		}
+		e.Time, e.Duration = 0, 0
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestTranscriptRecorder(t *testing.T) {
+	transcript := recordSession(t, "echo a && echo b\nfail x\nexit\n", false)
+	assert.Contains(t, transcript, `"data":"echo a && echo b"`)
+
+	assert.Equal(t, []TranscriptEvent{
+		{Type: TranscriptStart, Width: 80, Height: 24},
+		{Type: TranscriptPrompt, Data: "cle"},
+		{Type: TranscriptInput, Data: "echo a && echo b"},
+		{Type: TranscriptCommand, Data: "echo a && echo b"},
+		{Type: TranscriptOutput, Data: "a\n"},
+		{Type: TranscriptOutput, Data: "b\n"},
+		{Type: TranscriptResult},
+		// error output of the ErrorHandler follows the result
+		{Type: TranscriptPrompt, Data: "cle"},
+		{Type: TranscriptInput, Data: "fail x"},
+		{Type: TranscriptCommand, Data: "fail x"},
+		{Type: TranscriptResult, Error: "failed with x"},
+		{Type: TranscriptOutput, Data: "ERROR: failed with x\n"},
+		{Type: TranscriptPrompt, Data: "cle"},
+		{Type: TranscriptInput, Data: "exit"},
+		{Type: TranscriptCommand, Data: "exit"},
+		{Type: TranscriptResult},
+		{Type: TranscriptEnd},
+	}, decodeTranscript(t, transcript))
+}
+
+func TestTranscriptRecorderCommandsOnly(t *testing.T) {
+	transcript := recordSession(t, "echo a\nfail x\n", true)
+	assert.Equal(t, []TranscriptEvent{
+		{Type: TranscriptStart, Width: 80, Height: 24, CommandsOnly: true},
+		{Type: TranscriptCommand, Data: "echo a"},
+		{Type: TranscriptResult},
+		{Type: TranscriptCommand, Data: "fail x"},
+		{Type: TranscriptResult, Error: "failed with x"},
+		{Type: TranscriptEnd},
+	}, decodeTranscript(t, transcript))
+}
+
+func TestReplayTranscript(t *testing.T) {
+	transcript := recordSession(t, "echo a && echo b\nfail x\necho c\nexit\necho d\n", false)
+
+	consoletest.WithOutputMocks(func(_ *consoletest.MockInput, output *consoletest.MockOutput) {
+		diffs, err := ReplayTranscript(prepareTranscriptCLE(func(s string) string { return s }), strings.NewReader(transcript))
+		require.NoError(t, err)
+		assert.Empty(t, diffs)
+		// output is captured during replay
+		assert.Empty(t, output.String())
+
+		cle := prepareTranscriptCLE(func(s string) string {
+			if s == "b" {
+				return "B"
+			}
+			return s
+		})
+		cle.RegisterCommand(NewCustomCommand("fail", nil, func([]string) error { return nil }))
+		diffs, err = ReplayTranscript(cle, strings.NewReader(transcript))
+		require.NoError(t, err)
+		assert.Equal(t, []ReplayDiff{
+			{Line: "echo a && echo b", ExpectedOutput: "a\nb\n", ActualOutput: "a\nB\n"},
+			{Line: "fail x", ExpectedError: "failed with x"},
+		}, diffs)
+		assert.Equal(t, "$ echo a && echo b\n a\n-b\n+B\n", diffs[0].String())
+		assert.Equal(t, "$ fail x\n-ERROR: failed with x\n+ERROR: \n", diffs[1].String())
+	})
+}
+
+func TestReplayTranscriptCommandsOnly(t *testing.T) {
+	transcript := recordSession(t, "echo a\nfail x\n", true)
+
+	consoletest.WithOutputMocks(func(_ *consoletest.MockInput, _ *consoletest.MockOutput) {
+		// output is not compared
+		diffs, err := ReplayTranscript(prepareTranscriptCLE(strings.ToUpper), strings.NewReader(transcript))
+		require.NoError(t, err)
+		assert.Empty(t, diffs)
+	})
+
+	_, err := ReplayTranscript(NewEnvironment(), strings.NewReader("{}\nfoo\n"))
+	assert.ErrorContains(t, err, "invalid transcript event 2")
+}
+
+func TestReplayNestedScript(t *testing.T) {
+	transcript := strings.Join([]string{
+		`{"time":0,"type":"start"}`,
+		`{"time":1,"type":"command","data":"source script.txt"}`,
+		`{"time":2,"type":"command","data":"echo a"}`,
+		`{"time":3,"type":"output","data":"a\n"}`,
+		`{"time":4,"type":"result"}`,
+		`{"time":5,"type":"result","error":"failed"}`,
+	}, "\n")
+
+	commands, _, err := readTranscript(strings.NewReader(transcript))
+	require.NoError(t, err)
+	require.Len(t, commands, 1)
+	assert.Equal(t, "source script.txt", commands[0].line)
+	assert.Equal(t, "a\n", commands[0].output.String())
+	assert.Equal(t, "failed", commands[0].err)
+}
+
+func TestDiffLines(t *testing.T) {
+	assert.Equal(t, " a\n-b\n+x\n c\n+d\n", diffLines("a\nb\nc\n", "a\nx\nc\nd\n"))
+	assert.Equal(t, "-a\n+a\n", diffLines("a\n", "a"))
+	assert.Equal(t, "", diffLines("", ""))
+}
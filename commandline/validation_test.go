@@ -0,0 +1,57 @@
+package commandline
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/DENICeG/go-console/v2/consoletest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandLineEnvironmentValidation(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		input.PutString("print foo bad\n\r\r\rbar\nexit\n")
+
+		cle, _, sb := prepareTestCLE()
+		cle.RegisterCommand(NewValidatedCustomCommand("print", nil,
+			func(args []string) error {
+				for i, a := range args {
+					if a == "bad" {
+						return NewErrInvalidArgument(i, fmt.Sprintf("invalid argument %q", a))
+					}
+				}
+				return nil
+			},
+			newPrintHandler(sb)))
+
+		assert.NoError(t, cle.Run())
+		assert.Equal(t, ">foo<>bar<|", sb.String())
+		assert.True(t, strings.Contains(output.String(), "\r\n"+strings.Repeat(" ", 15)+styleUnknownCommand+"^~~"+ansiReset))
+		assert.True(t, strings.Contains(output.String(), "invalid argument \"bad\""))
+		input.AssertBufferConsumed(t)
+	})
+}
+
+func TestValidateCommand(t *testing.T) {
+	cle := NewEnvironment()
+	cle.RegisterCommand(NewExitCommand("exit"))
+	cle.RegisterCommand(NewValidatedCustomCommand("check", nil,
+		func(args []string) error {
+			if len(args) > 1 {
+				return NewErrInvalidArgument(1, "too many arguments")
+			}
+			if len(args) == 1 && args[0] == "fail" {
+				return fmt.Errorf("failed")
+			}
+			return nil
+		}, nil))
+
+	assert.NoError(t, cle.ValidateCommand(nil))
+	assert.NoError(t, cle.ValidateCommand([]string{"exit", "foo"}))
+	assert.NoError(t, cle.ValidateCommand([]string{"unknown"}))
+	assert.NoError(t, cle.ValidateCommand([]string{"check", "foo"}))
+	assert.EqualError(t, cle.ValidateCommand([]string{"check", "fail"}), "failed")
+	assert.Equal(t, ErrInvalidArgument{Index: 2, Message: "too many arguments"}, cle.ValidateCommand([]string{"check", "a", "b"}))
+}
@@ -0,0 +1,293 @@
+package commandline
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/DENICeG/go-console/v2"
+)
+
+// SetVar sets a session variable that is referenced as $NAME or ${NAME} in command lines.
+func (b *Environment) SetVar(name, value string) {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+	b.vars[name] = value
+}
+
+// UnsetVar removes a session variable and returns true if it was existent before.
+func (b *Environment) UnsetVar(name string) bool {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+	_, exists := b.vars[name]
+	if exists {
+		delete(b.vars, name)
+	}
+	return exists
+}
+
+// Var returns the value of a session variable. OS environment variables are used as fallback if UseOSEnvironment is set.
+func (b *Environment) Var(name string) (string, bool) {
+	b.stateMutex.RLock()
+	value, exists := b.vars[name]
+	b.stateMutex.RUnlock()
+	if exists {
+		return value, true
+	}
+	if b.UseOSEnvironment {
+		return os.LookupEnv(name)
+	}
+	return "", false
+}
+
+// varNames returns the sorted names of all variables including OS environment variables if UseOSEnvironment is set.
+func (b *Environment) varNames() []string {
+	unique := make(map[string]bool)
+	for name := range b.varMap() {
+		unique[name] = true
+	}
+	if b.UseOSEnvironment {
+		for _, env := range os.Environ() {
+			if name, _, ok := strings.Cut(env, "="); ok && isVarName(name) {
+				unique[name] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(unique))
+	for name := range unique {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// varMap returns a copy of all session variables.
+func (b *Environment) varMap() map[string]string {
+	b.stateMutex.RLock()
+	defer b.stateMutex.RUnlock()
+	return maps.Clone(b.vars)
+}
+
+// ParseLine parses a command line like ParseCommand and expands all variables in unquoted and double quoted text.
+//
+// Undefined variables expand to an empty string. Command parts that are empty after expansion are omitted.
+func (b *Environment) ParseLine(line string) (cmd []string, isComplete bool) {
+	tokens, isComplete := TokenizeCommand(line)
+
+	cmd = make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		var sb strings.Builder
+		for _, s := range t.Segments {
+			if s.Kind == SegmentPlain || s.Kind == SegmentDoubleQuoted {
+				sb.WriteString(b.expandVars(s.Value))
+			} else {
+				sb.WriteString(s.Value)
+			}
+		}
+		if sb.Len() > 0 {
+			cmd = append(cmd, sb.String())
+		}
+	}
+
+	return cmd, isComplete
+}
+
+// expandVars replaces all variable references in str.
+func (b *Environment) expandVars(str string) string {
+	if !strings.Contains(str, "$") {
+		return str
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(str); i++ {
+		if str[i] != '$' {
+			sb.WriteByte(str[i])
+			continue
+		}
+
+		name, length := parseVarReference(str[i+1:])
+		if length == 0 {
+			// sole dollar sign
+			sb.WriteByte('$')
+			continue
+		}
+		value, _ := b.Var(name)
+		sb.WriteString(value)
+		i += length
+	}
+	return sb.String()
+}
+
+// parseVarReference returns the variable name at the beginning of str, which directly follows a dollar sign, and the number of consumed bytes.
+func parseVarReference(str string) (string, int) {
+	if strings.HasPrefix(str, "{") {
+		end := strings.IndexByte(str, '}')
+		if end < 0 || !isVarName(str[1:end]) {
+			return "", 0
+		}
+		return str[1:end], end + 1
+	}
+
+	length := 0
+	for length < len(str) && isVarNameChar(str[length], length == 0) {
+		length++
+	}
+	return str[:length], length
+}
+
+func isVarNameChar(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
+
+func isVarName(str string) bool {
+	if len(str) == 0 {
+		return false
+	}
+	for i := 0; i < len(str); i++ {
+		if !isVarNameChar(str[i], i == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// varCompletionOptions returns completion options for a variable reference at the end of entry.
+func (b *Environment) varCompletionOptions(entry string) ([]CompletionOption, bool) {
+	start := strings.LastIndexByte(entry, '$')
+	if start < 0 {
+		return nil, false
+	}
+
+	prefix := entry[start+1:]
+	braced := strings.HasPrefix(prefix, "{")
+	if braced {
+		prefix = prefix[1:]
+	}
+	if len(prefix) > 0 && !isVarName(prefix) {
+		return nil, false
+	}
+
+	options := make([]CompletionOption, 0)
+	for _, name := range b.varNames() {
+		replacement := entry[:start] + "$" + name
+		if braced {
+			replacement = entry[:start] + "${" + name + "}"
+		}
+		value, _ := b.Var(name)
+		options = append(options, &varCompletionOption{completionOption{label: "$" + name, replacement: replacement, description: value}})
+	}
+	return options, true
+}
+
+// varCompletionOption completes a variable reference, which is not escaped when inserted.
+type varCompletionOption struct {
+	completionOption
+}
+
+// isVarReference returns true if option completes a variable reference.
+func isVarReference(option CompletionOption) bool {
+	_, ok := option.(*varCompletionOption)
+	return ok
+}
+
+type setCommand struct {
+	name string
+	env  *Environment
+}
+
+// NewSetCommand returns a named command to set session variables like set ZONE example.com. Multiple arguments are joined by spaces.
+func NewSetCommand(name string, env *Environment) Command {
+	return Describe(&setCommand{name: name, env: env}, CommandInfo{
+		Description: "set a variable",
+		Usage:       fmt.Sprintf("Usage: %s <name> [value...]\n", name),
+	})
+}
+
+func (c *setCommand) Name() string {
+	return c.name
+}
+
+func (c *setCommand) GetCompletionOptions(_ []string, entryIndex int) []CompletionOption {
+	if entryIndex == 1 {
+		return PrepareCompletionOptions(c.env.varNames(), false)
+	}
+	return nil
+}
+
+func (c *setCommand) Validate(args []string) error {
+	if len(args) > 0 && !isVarName(args[0]) {
+		return NewErrInvalidArgument(0, fmt.Sprintf("invalid variable name %q", args[0]))
+	}
+	return nil
+}
+
+func (c *setCommand) Exec(args []string) error {
+	if len(args) == 0 {
+		return ErrInvalidUsage{Err: fmt.Errorf("missing variable name"), Usage: fmt.Sprintf("Usage: %s <name> [value...]\n", c.name)}
+	}
+	if err := c.Validate(args); err != nil {
+		return err
+	}
+	c.env.SetVar(args[0], strings.Join(args[1:], " "))
+	return nil
+}
+
+type unsetCommand struct {
+	name string
+	env  *Environment
+}
+
+// NewUnsetCommand returns a named command to remove session variables.
+func NewUnsetCommand(name string, env *Environment) Command {
+	return Describe(&unsetCommand{name: name, env: env}, CommandInfo{
+		Description: "remove variables",
+		Usage:       fmt.Sprintf("Usage: %s <name>...\n", name),
+	})
+}
+
+func (c *unsetCommand) Name() string {
+	return c.name
+}
+
+func (c *unsetCommand) GetCompletionOptions(_ []string, _ int) []CompletionOption {
+	vars := c.env.varMap()
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return PrepareCompletionOptions(names, false)
+}
+
+func (c *unsetCommand) Exec(args []string) error {
+	if len(args) == 0 {
+		return ErrInvalidUsage{Err: fmt.Errorf("missing variable name"), Usage: fmt.Sprintf("Usage: %s <name>...\n", c.name)}
+	}
+	for _, name := range args {
+		if !c.env.UnsetVar(name) {
+			return fmt.Errorf("unknown variable %q", name)
+		}
+	}
+	return nil
+}
+
+// NewVarsCommand returns a named command that lists all session variables.
+func NewVarsCommand(name string, env *Environment) Command {
+	return Describe(NewParameterlessCommand(name, func([]string) error {
+		vars := env.varMap()
+		names := make([]string, 0, len(vars))
+		for name := range vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if _, err := console.Printlnf("%s=%s", name, Quote(vars[name])); err != nil {
+				return err
+			}
+		}
+		return nil
+	}), CommandInfo{Description: "list all variables"})
+}
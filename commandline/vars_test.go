@@ -0,0 +1,113 @@
+package commandline
+
+import (
+	"os"
+	"testing"
+
+	"github.com/DENICeG/go-console/v2/consoletest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLine(t *testing.T) {
+	cle := NewEnvironment()
+	cle.SetVar("ZONE", "example.com")
+	cle.SetVar("EMPTY", "")
+
+	cmd, isComplete := cle.ParseLine(`info $ZONE "www.$ZONE" '$ZONE' \$ZONE "\$ZONE" ${ZONE}s $ZONEs $ $EMPTY "$EMPTY" ${ZONE $1`)
+	assert.True(t, isComplete)
+	assert.Equal(t, []string{"info", "example.com", "www.example.com", "$ZONE", "$ZONE", "$ZONE", "example.coms", "$", "${ZONE", "$1"}, cmd)
+
+	cmd, isComplete = cle.ParseLine(`info "$ZONE`)
+	assert.False(t, isComplete)
+	assert.Equal(t, []string{"info", "example.com"}, cmd)
+}
+
+func TestVarOSEnvironment(t *testing.T) {
+	t.Setenv("GO_CONSOLE_TEST_VAR", "from os")
+	cle := NewEnvironment()
+
+	_, exists := cle.Var("GO_CONSOLE_TEST_VAR")
+	assert.False(t, exists)
+
+	cle.UseOSEnvironment = true
+	value, exists := cle.Var("GO_CONSOLE_TEST_VAR")
+	assert.True(t, exists)
+	assert.Equal(t, "from os", value)
+
+	// session variables take precedence
+	cle.SetVar("GO_CONSOLE_TEST_VAR", "from session")
+	cmd, _ := cle.ParseLine("echo $GO_CONSOLE_TEST_VAR")
+	assert.Equal(t, []string{"echo", "from session"}, cmd)
+	assert.True(t, cle.UnsetVar("GO_CONSOLE_TEST_VAR"))
+	assert.Contains(t, cle.varNames(), "GO_CONSOLE_TEST_VAR")
+	assert.NoError(t, os.Unsetenv("GO_CONSOLE_TEST_VAR"))
+}
+
+func TestVarCompletion(t *testing.T) {
+	cle, _, _ := prepareTestCLE()
+	cle.SetVar("ZONE", "example.com")
+	cle.SetVar("ZONEFILE", "db.example")
+	cle.SetVar("HANDLE", "DENIC-1")
+
+	options := matchOptions(cle.GetCompletionOptions([]string{"print", "$Z"}, 1), "$Z", nil)
+	assert.Equal(t, []string{"$ZONE", "$ZONEFILE"}, replacements(options))
+	assert.Equal(t, "example.com", getDescription(options[0]))
+
+	options = matchOptions(cle.GetCompletionOptions([]string{"print", "www.${H"}, 1), "www.${H", nil)
+	assert.Equal(t, []string{"www.${HANDLE}"}, replacements(options))
+
+	// no variable reference at the end of the entry
+	assert.Equal(t, 3, len(cle.GetCompletionOptions([]string{"print", "$ZONE."}, 1)))
+
+	// variable references are not escaped when inserted, other dollar signs are
+	options = matchOptions(cle.GetCompletionOptions([]string{"print", "$zone"}, 1), "$zone", CaseInsensitivePrefixMatcher)
+	require.NotEmpty(t, options)
+	line, _ := completeCommandPart("print $zone", "$zone", 6, options[0].Replacement(), isVarReference(options[0]))
+	assert.Equal(t, "print $ZONE", line)
+	line, _ = completeCommandPart("print p", "p", 6, "price$1.txt", false)
+	assert.Equal(t, `print price\$1.txt`, line)
+}
+
+func TestVarValidation(t *testing.T) {
+	cle := NewEnvironment()
+	cle.RegisterCommand(NewValidatedCustomCommand("check", nil,
+		func(args []string) error {
+			for i, a := range args {
+				if a == "bad" {
+					return NewErrInvalidArgument(i, "bad argument")
+				}
+			}
+			return nil
+		}, nil))
+	cle.SetVar("ARG", "bad")
+	cle.SetVar("EMPTY", "")
+
+	// variables are not expanded in single quotes
+	assert.NoError(t, cle.ValidateCommandLine(`check '$ARG' \$ARG`))
+	assert.Equal(t, NewErrInvalidArgument(1, "bad argument"), cle.ValidateCommandLine(`check "$ARG"`))
+	assert.Equal(t, NewErrInvalidArgument(2, "bad argument"), cle.ValidateCommandLine(`check $EMPTY $ARG`))
+	assert.Equal(t, NewErrInvalidArgument(1, "bad argument"), cle.ValidateCommand([]string{"check", "$ARG"}))
+}
+
+func TestCommandLineEnvironmentVars(t *testing.T) {
+	consoletest.WithOutputMocks(func(input *consoletest.MockInput, output *consoletest.MockOutput) {
+		cle, _, sb := prepareTestCLE()
+		cle.RegisterCommand(NewSetCommand("set", cle))
+		cle.RegisterCommand(NewUnsetCommand("unset", cle))
+		cle.RegisterCommand(NewVarsCommand("vars", cle))
+
+		input.PutString("set ZONE example.com\nset GREETING hello world\nprint $Z\t '$ZONE' \"$GREETING!\"\nvars\nunset ZONE\nprint x${ZONE}x\nexit\n")
+		require.NoError(t, cle.Run())
+		input.AssertBufferConsumed(t)
+
+		assert.Equal(t, ">example.com<>$ZONE<>hello world!<|>xx<|", sb.String())
+		assert.Contains(t, output.String(), "GREETING=\"hello world\"\nZONE=example.com\n")
+
+		// history keeps the variable references
+		entry, ok := cle.history.GetHistoryEntry(1)
+		require.True(t, ok)
+		assert.Equal(t, []string{"print", "x${ZONE}x"}, entry)
+	})
+}
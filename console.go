@@ -32,6 +32,13 @@ type Input interface {
 	EndReadKey() error
 }
 
+// InteractiveInput is implemented by inputs that know whether they are connected to a terminal. Inputs not implementing it are considered interactive.
+type InteractiveInput interface {
+	Input
+	// IsInteractive returns false when the input is not read from a terminal, e.g. from a pipe or file.
+	IsInteractive() bool
+}
+
 // Output defines functionality to handle console output and os responses.
 type Output interface {
 	Print(string) (int, error)
@@ -318,6 +325,18 @@ func (d *defaultInput) EndReadKey() error {
 	return endReadKey()
 }
 
+func (d *defaultInput) IsInteractive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// IsInteractive returns false when input is not read from a terminal, e.g. when Stdin is a pipe or file. Only ReadLine should be used for non-interactive input.
+func IsInteractive() bool {
+	if i, ok := DefaultInput.(InteractiveInput); ok {
+		return i.IsInteractive()
+	}
+	return true
+}
+
 // BeginReadKey opens a raw TTY and allows you to use ReadKey.
 func BeginReadKey() error {
 	return DefaultInput.BeginReadKey()
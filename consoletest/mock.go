@@ -2,6 +2,8 @@ package consoletest
 
 import (
 	"fmt"
+	"io"
+	"strings"
 	"testing"
 
 	"github.com/DENICeG/go-console/v2"
@@ -19,10 +21,12 @@ type MockInput struct {
 	buffer          []ReadKeyResult
 	bufferPos       int
 	isReadKeyActive bool
+	// NonInteractive simulates input from a pipe or file.
+	NonInteractive bool
 }
 
 func NewMockInput() *MockInput {
-	return &MockInput{make([]ReadKeyResult, 0), 0, false}
+	return &MockInput{buffer: make([]ReadKeyResult, 0)}
 }
 
 func (m *MockInput) PutString(buffer string) {
@@ -49,6 +53,11 @@ func (m *MockInput) PutKeys(keys ...console.Key) {
 	}
 }
 
+// PutKeyWithRune appends a key event with rune like KeyAlt and 'f' for Alt+F.
+func (m *MockInput) PutKeyWithRune(key console.Key, r rune) {
+	m.buffer = append(m.buffer, ReadKeyResult{Key: key, Rune: r, Error: nil})
+}
+
 func (m *MockInput) BufferConsumed() bool {
 	return m.bufferPos >= len(m.buffer)
 }
@@ -57,8 +66,29 @@ func (m *MockInput) AssertBufferConsumed(t *testing.T) bool {
 	return assert.True(t, m.BufferConsumed(), "Not all input buffer chars have been consumed")
 }
 
+// ReadLine returns all buffered runes up to the next Enter key. io.EOF is returned when the buffer is exhausted.
 func (m *MockInput) ReadLine() (string, error) {
-	panic("ReadLine not available for mock")
+	var sb strings.Builder
+	for !m.BufferConsumed() {
+		result := m.buffer[m.bufferPos]
+		m.bufferPos++
+
+		switch result.Key {
+		case console.KeyEnter:
+			return sb.String(), nil
+		case console.KeySpace:
+			sb.WriteRune(' ')
+		case console.KeyTab:
+			sb.WriteRune('\t')
+		case 0:
+			sb.WriteRune(result.Rune)
+		}
+	}
+	return sb.String(), io.EOF
+}
+
+func (m *MockInput) IsInteractive() bool {
+	return !m.NonInteractive
 }
 
 func (m *MockInput) ReadPassword() (string, error) {
@@ -95,7 +125,53 @@ func (m *MockInput) EndReadKey() error {
 	return nil
 }
 
+type MockOutput struct {
+	buffer        strings.Builder
+	Width, Height int
+	Colors        bool
+	ExitCode      int
+	HasExited     bool
+}
+
+func NewMockOutput(width, height int) *MockOutput {
+	return &MockOutput{Width: width, Height: height, Colors: true, ExitCode: -1}
+}
+
+func (m *MockOutput) Print(str string) (int, error) {
+	return m.buffer.WriteString(str)
+}
+
+func (m *MockOutput) GetSize() (int, int, error) {
+	return m.Width, m.Height, nil
+}
+
+func (m *MockOutput) SupportsColors() bool {
+	return m.Colors
+}
+
+func (m *MockOutput) Exit(code int) {
+	m.ExitCode = code
+	m.HasExited = true
+}
+
+// String returns everything that has been printed so far.
+func (m *MockOutput) String() string {
+	return m.buffer.String()
+}
+
+// Reset discards everything that has been printed so far.
+func (m *MockOutput) Reset() {
+	m.buffer.Reset()
+}
+
 func WithMocks(f func(input *MockInput)) {
+	WithOutputMocks(func(input *MockInput, _ *MockOutput) {
+		f(input)
+	})
+}
+
+// WithOutputMocks works like WithMocks but also redirects all output to a mocked 80x24 terminal.
+func WithOutputMocks(f func(input *MockInput, output *MockOutput)) {
 	oldInput := console.DefaultInput
 	oldOutput := console.DefaultOutput
 
@@ -106,6 +182,8 @@ func WithMocks(f func(input *MockInput)) {
 
 	input := NewMockInput()
 	console.DefaultInput = input
+	output := NewMockOutput(80, 24)
+	console.DefaultOutput = output
 
-	f(input)
+	f(input, output)
 }
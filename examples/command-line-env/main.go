@@ -1,7 +1,11 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/DENICeG/go-console/v2"
 	"github.com/DENICeG/go-console/v2/commandline"
@@ -13,6 +17,42 @@ func main() {
 	cle := commandline.NewEnvironment()
 
 	cle.RegisterCommand(commandline.NewExitCommand("exit"))
+	cle.RegisterCommand(commandline.NewHelpCommand("help", cle))
+	cle.RegisterCommand(commandline.NewAliasCommand("alias", cle))
+	cle.RegisterCommand(commandline.NewUnaliasCommand("unalias", cle))
+	cle.SetAlias("q", "exit")
+	cle.RegisterCommand(commandline.NewSetCommand("set", cle))
+	cle.RegisterCommand(commandline.NewUnsetCommand("unset", cle))
+	cle.RegisterCommand(commandline.NewVarsCommand("vars", cle))
+	cle.RegisterCommand(commandline.NewSourceCommand("source", cle))
+	cle.RegisterCommand(commandline.NewGrepCommand("grep"))
+	cle.RegisterCommand(commandline.NewHeadCommand("head"))
+	cle.RegisterCommand(commandline.NewTailCommand("tail"))
+	cle.RegisterCommand(commandline.NewWcCommand("wc"))
+	cle.EnableGlob("grep", "head", "tail", "wc")
+	cle.RegisterCommand(commandline.NewJobsCommand("jobs", cle))
+	cle.RegisterCommand(commandline.NewFgCommand("fg", cle))
+	cle.RegisterCommand(commandline.NewWaitCommand("wait", cle))
+	cle.RegisterCommand(commandline.NewKillCommand("kill", cle))
+
+	// try: sleep 5 &
+	cle.RegisterCommand(commandline.NewContextCommand("sleep", nil, func(ctx context.Context, args []string) error {
+		seconds := 1
+		if len(args) > 0 {
+			var err error
+			if seconds, err = strconv.Atoi(args[0]); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-time.After(time.Duration(seconds) * time.Second):
+			console.Printlnf("-> slept %d seconds", seconds)
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}))
 
 	cle.ExecUnknownCommand = func(cmd string, args []string) error {
 		console.Printlnf("Unknown command %q", cmd)
@@ -43,6 +83,28 @@ func main() {
 			return nil
 		}))
 
+	quack := commandline.NewCommandBuilder("quack").
+		Description("let a duck quack").
+		Example("quack -n 3 --volume loud donald")
+	quack.IntFlag("times", "number of quacks").Short('n').Default("1")
+	quack.EnumFlag("volume", "how loud to quack", "quiet", "loud").Default("quiet")
+	quack.StringArg("duck", "duck that quacks").Required()
+	cle.RegisterCommand(quack.Build(func(args *commandline.ParsedArgs) error {
+		sound := "quack"
+		if args.String("volume") == "loud" {
+			sound = "QUACK"
+		}
+		for i := 0; i < args.Int("times"); i++ {
+			console.Printlnf("-> %s: %s!", args.String("duck"), sound)
+		}
+		return nil
+	}))
+
+	if len(os.Args) > 1 {
+		// one-shot mode: command-line-env quack -n 2 donald
+		os.Exit(cle.RunArgs(os.Args[1:]))
+	}
+
 	if err := cle.Run(); err != nil {
 		console.Println()
 		if !errors.Is(err, commandline.ErrCtrlC) {
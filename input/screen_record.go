@@ -0,0 +1,148 @@
+package input
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DENICeG/go-console/v2"
+)
+
+type recordedCell struct {
+	r rune
+	// style holds the SGR sequences for colors different from the default color
+	style string
+}
+
+// recordingScreen passes every flushed frame as ANSI escape sequences to a console.ScreenRecorder, because screen backends write to the terminal directly instead of using the console output.
+type recordingScreen struct {
+	screen
+	recorder console.ScreenRecorder
+	cells    [][]recordedCell
+	// rows holds the last recorded rows to only record changed ones
+	rows    []string
+	cursorX int
+	cursorY int
+}
+
+// wrapRecordingScreen returns s wrapped by a recordingScreen if the console output is recorded.
+func wrapRecordingScreen(s screen) screen {
+	recorder, ok := console.FindScreenRecorder(console.DefaultOutput)
+	if !ok {
+		return s
+	}
+	return newRecordingScreen(s, recorder)
+}
+
+func newRecordingScreen(s screen, recorder console.ScreenRecorder) *recordingScreen {
+	// switch to the alternate screen like the backends do
+	recorder.RecordScreen("\x1b[?1049h\x1b[H\x1b[2J")
+	return &recordingScreen{screen: s, recorder: recorder, cursorX: -1, cursorY: -1}
+}
+
+func (s *recordingScreen) Clear() {
+	s.screen.Clear()
+	width, height := s.screen.Size()
+	s.cells = make([][]recordedCell, height)
+	for y := range s.cells {
+		s.cells[y] = make([]recordedCell, width)
+	}
+}
+
+func (s *recordingScreen) SetCell(x, y int, r rune) {
+	s.screen.SetCell(x, y, r)
+	s.setCell(x, y, recordedCell{r: r})
+}
+
+func (s *recordingScreen) SetCellColored(x, y int, r rune, foreground, background RGB) {
+	s.screen.SetCellColored(x, y, r, foreground, background)
+	defaultColor := s.screen.GetDefaultColor()
+	style := ""
+	if foreground != defaultColor {
+		style += fmt.Sprintf("\x1b[38;2;%d;%d;%dm", foreground.R, foreground.G, foreground.B)
+	}
+	if background != defaultColor {
+		style += fmt.Sprintf("\x1b[48;2;%d;%d;%dm", background.R, background.G, background.B)
+	}
+	s.setCell(x, y, recordedCell{r: r, style: style})
+}
+
+func (s *recordingScreen) setCell(x, y int, cell recordedCell) {
+	if y >= 0 && y < len(s.cells) && x >= 0 && x < len(s.cells[y]) {
+		s.cells[y][x] = cell
+	}
+}
+
+func (s *recordingScreen) SetCursor(x, y int) {
+	s.screen.SetCursor(x, y)
+	s.cursorX, s.cursorY = x, y
+}
+
+func (s *recordingScreen) Flush() {
+	s.screen.Flush()
+
+	var sb strings.Builder
+	if len(s.rows) != len(s.cells) {
+		// size changed, redraw everything
+		sb.WriteString("\x1b[H\x1b[2J")
+		s.rows = make([]string, len(s.cells))
+	}
+	for y := range s.cells {
+		row := s.renderRow(y)
+		if row != s.rows[y] {
+			fmt.Fprintf(&sb, "\x1b[%d;1H%s\x1b[K", y+1, row)
+			s.rows[y] = row
+		}
+	}
+
+	if s.cursorX < 0 || s.cursorY < 0 {
+		sb.WriteString("\x1b[?25l")
+	} else {
+		fmt.Fprintf(&sb, "\x1b[%d;%dH\x1b[?25h", s.cursorY+1, s.cursorX+1)
+	}
+	s.recorder.RecordScreen(sb.String())
+}
+
+// renderRow returns the ANSI representation of a row without trailing empty cells.
+func (s *recordingScreen) renderRow(y int) string {
+	cells := s.cells[y]
+	end := len(cells)
+	for end > 0 && cells[end-1] == (recordedCell{}) {
+		end--
+	}
+
+	var sb strings.Builder
+	style := ""
+	for _, cell := range cells[:end] {
+		if cell.style != style {
+			if style != "" {
+				sb.WriteString("\x1b[0m")
+			}
+			sb.WriteString(cell.style)
+			style = cell.style
+		}
+
+		if cell.r == 0 {
+			sb.WriteRune(' ')
+		} else {
+			sb.WriteRune(cell.r)
+		}
+	}
+	if style != "" {
+		sb.WriteString("\x1b[0m")
+	}
+	return sb.String()
+}
+
+func (s *recordingScreen) PollEvent() event {
+	e := s.screen.PollEvent()
+	if key, ok := e.(keyEvent); ok {
+		s.recorder.RecordKey(key.Key, key.Rune)
+	}
+	return e
+}
+
+func (s *recordingScreen) Close() {
+	s.screen.Close()
+	// leave the alternate screen
+	s.recorder.RecordScreen("\x1b[?25h\x1b[?1049l")
+}
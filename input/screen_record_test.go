@@ -0,0 +1,91 @@
+package input
+
+import (
+	"testing"
+
+	"github.com/DENICeG/go-console/v2"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testScreen is a screen of the given size that returns queued events.
+type testScreen struct {
+	width, height int
+	events        []event
+	flushed       int
+	closed        bool
+}
+
+func (s *testScreen) Clear()                                  {}
+func (s *testScreen) Size() (int, int)                        { return s.width, s.height }
+func (s *testScreen) SetCell(int, int, rune)                  {}
+func (s *testScreen) SetCellColored(int, int, rune, RGB, RGB) {}
+func (s *testScreen) GetDefaultColor() RGB                    { return RGB{} }
+func (s *testScreen) Flush()                                  { s.flushed++ }
+func (s *testScreen) SetCursor(int, int)                      {}
+func (s *testScreen) Close()                                  { s.closed = true }
+func (s *testScreen) PollEvent() event {
+	e := s.events[0]
+	s.events = s.events[1:]
+	return e
+}
+
+// testRecorder collects recorded screen data and keys.
+type testRecorder struct {
+	console.Output
+	screen []string
+	keys   []console.Key
+}
+
+func (r *testRecorder) RecordScreen(data string) {
+	r.screen = append(r.screen, data)
+}
+
+func (r *testRecorder) RecordKey(key console.Key, _ rune) {
+	r.keys = append(r.keys, key)
+}
+
+func TestRecordingScreen(t *testing.T) {
+	backend := &testScreen{width: 6, height: 2, events: []event{keyEvent{Key: console.KeyUp}, resizeEvent{}}}
+	recorder := &testRecorder{}
+	s := newRecordingScreen(backend, recorder)
+
+	s.Clear()
+	printCells(s, "ab", 1, 0)
+	s.SetCellColored(0, 1, 'x', RGB{255, 0, 0}, RGB{})
+	s.SetCellColored(1, 1, 'y', RGB{}, RGB{})
+	s.SetCell(10, 10, 'z')
+	s.SetCursor(2, 1)
+	s.Flush()
+
+	// only changed rows are recorded
+	s.Clear()
+	printCells(s, "ab", 1, 0)
+	s.SetCursor(-1, -1)
+	s.Flush()
+
+	assert.Equal(t, keyEvent{Key: console.KeyUp}, s.PollEvent())
+	assert.Equal(t, resizeEvent{}, s.PollEvent())
+	s.Close()
+
+	assert.Equal(t, 2, backend.flushed)
+	assert.True(t, backend.closed)
+	assert.Equal(t, []console.Key{console.KeyUp}, recorder.keys)
+	assert.Equal(t, []string{
+		"\x1b[?1049h\x1b[H\x1b[2J",
+		"\x1b[H\x1b[2J\x1b[1;1H ab\x1b[K\x1b[2;1H\x1b[38;2;255;0;0mx\x1b[0my\x1b[K\x1b[2;3H\x1b[?25h",
+		"\x1b[2;1H\x1b[K\x1b[?25l",
+		"\x1b[?25h\x1b[?1049l",
+	}, recorder.screen)
+}
+
+func TestWrapRecordingScreen(t *testing.T) {
+	oldOutput := console.DefaultOutput
+	defer func() { console.DefaultOutput = oldOutput }()
+
+	backend := &testScreen{}
+	assert.Same(t, backend, wrapRecordingScreen(backend))
+
+	console.DefaultOutput = &testRecorder{}
+	assert.IsType(t, &recordingScreen{}, wrapRecordingScreen(backend))
+}
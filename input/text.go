@@ -255,6 +255,7 @@ func Text(str string) (string, bool, error) {
 	if err != nil {
 		return "", false, err
 	}
+	screen = wrapRecordingScreen(screen)
 	defer screen.Close()
 
 	editor := newTextEditor(str)
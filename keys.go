@@ -44,6 +44,8 @@ const (
 	KeyTab = Key(keyboard.KeyTab)
 	// KeySpace represents the space key
 	KeySpace = Key(keyboard.KeySpace)
+	// KeyAlt represents a key combination of Alt and the returned rune. It is not reported on Windows.
+	KeyAlt = Key(0xFF00)
 )
 
 func (k Key) String() string {
@@ -82,6 +84,8 @@ func (k Key) String() string {
 		return "Tab"
 	case KeySpace:
 		return "Space"
+	case KeyAlt:
+		return "Alt"
 
 	default:
 		return fmt.Sprintf("Key[%d]", k)
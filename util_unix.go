@@ -66,8 +66,8 @@ func beginReadKey() error {
 	}
 	newTermios := ttyOldTermios
 	newTermios.Iflag &^= syscall.ISTRIP | syscall.INLCR | syscall.ICRNL | syscall.IGNCR | syscall.IXOFF
-	newTermios.Lflag &^= syscall.ECHO | syscall.ICANON
-	//TODO catch Ctrl+C
+	// Ctrl+C is reported as KeyCtrlC instead of sending SIGINT
+	newTermios.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG
 	if _, _, err := syscall.Syscall(syscall.SYS_IOCTL, uintptr(in.Fd()), ioctlWriteTermios, uintptr(unsafe.Pointer(&newTermios))); err != 0 {
 		ttyIn.Close()
 		ttyOut.Close()
@@ -108,8 +108,8 @@ func readKey() (Key, rune, error) {
 			}
 
 			if len == 2 {
-				// malformed escape sequence
-				return KeyEscape, rune(buf[1]), nil
+				// escape prefix without sequence is sent for Alt+<key>
+				return KeyAlt, rune(buf[1]), nil
 			}
 
 			switch buf[2] {
@@ -121,6 +121,10 @@ func readKey() (Key, rune, error) {
 				return KeyLeft, 0, nil
 			case 67:
 				return KeyRight, 0, nil
+			case 72:
+				return KeyHome, 0, nil
+			case 70:
+				return KeyEnd, 0, nil
 
 			default:
 				// unknown escape sequence
@@ -48,6 +48,7 @@ func beginReadKey() error {
 	return nil
 }
 
+// readKey returns the next key. Home and End are reported by the keyboard package, but the Alt modifier is not, so KeyAlt is never returned on Windows.
 func readKey() (Key, rune, error) {
 	// does not work when inserting text
 	//char, key, err := keyboard.GetKey()